@@ -0,0 +1,32 @@
+package main
+
+import (
+	"pledge-backend/api/models"
+	"pledge-backend/api/services"
+	"pledge-backend/config"
+	"pledge-backend/db"
+	"pledge-backend/log"
+)
+
+// seed the first superadmin from [defaultadmin] and the default role permissions, does nothing once a superadmin exists
+func main() {
+
+	//init mysql
+	db.InitMysql()
+
+	db.Mysql.AutoMigrate(&models.Admin{})
+	db.Mysql.AutoMigrate(&models.RolePermission{})
+	if err := models.NewRolePermission().Seed(); err != nil {
+		log.Logger.Sugar().Fatal("seed role permissions err ", err)
+	}
+
+	action, err := services.NewAdmin().Bootstrap(config.Config.DefaultAdmin.Username, config.Config.DefaultAdmin.Password)
+	if err != nil {
+		log.Logger.Sugar().Fatal("bootstrap admin err ", err)
+	}
+	if action == "" {
+		log.Logger.Info("superadmin exists, nothing to do")
+	} else {
+		log.Logger.Info(action)
+	}
+}
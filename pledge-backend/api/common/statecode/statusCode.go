@@ -11,7 +11,15 @@ const (
 	CommonErrServerErr = 1000
 	ParameterEmptyErr  = 1001
 
-	TokenErr = 1102 //token error
+	TokenErr      = 1102 //token error
+	PermissionErr = 1103 //permission denied
+	RefreshErr    = 1104 //refresh token invalid or expired
+	SessionErr    = 1105 //session does not exist
+
+	// SiweMessageErr sign-in with ethereum
+	SiweMessageErr   = 1106 //message malformed, for another domain or chain, or expired
+	SiweSignatureErr = 1107 //signature does not match the address
+	SiweNonceErr     = 1108 //nonce unknown, expired or already used
 
 	// PNameEmpty muti-sign
 	PNameEmpty   = 1201 //p_name empty
@@ -19,6 +27,45 @@ const (
 	ChainIdErr   = 1203 //chain id error
 
 	NameOrPasswordErr = 1303 //name or password error
+	AdminExistErr     = 1304 //admin already exists
+	AdminNotExistErr  = 1305 //admin does not exist
+	AdminDisabledErr  = 1306 //admin disabled
+	NameFormatErr     = 1307 //name format error
+	PasswordFormatErr = 1308 //password format error
+	AdminSelfErr      = 1309 //can not disable yourself
+	RoleErr           = 1310 //unknown or read-only role
+	PermissionNameErr = 1311 //unknown permission
+	LastSuperAdminErr = 1312 //the last superadmin can not be removed
+
+	// WsMessageErr websocket
+	WsMessageErr     = 1401 //message can not be parsed
+	WsTopicErr       = 1402 //unknown topic
+	WsTooManyTopics  = 1403 //subscription limit reached
+	WsUnknownTypeErr = 1404 //unknown message type
+	WsHeartbeatErr   = 1405 //heartbeat timeout
+	WsConnLimitErr   = 1406 //connection limit reached
+
+	// SearchParamErr pool search
+	SearchParamErr  = 1501 //invalid filter or sort
+	SearchCursorErr = 1502 //invalid cursor
+
+	// RateLimitErr rate limit and api keys
+	RateLimitErr      = 1601 //too many requests
+	ApiKeyErr         = 1602 //api key invalid or revoked
+	ApiKeyNotExistErr = 1603 //api key does not exist
+	ApiKeyNameErr     = 1604 //api key name empty or too long
+
+	// AddressErr multi-sign
+	AddressErr         = 1701 //address is not a valid EIP-55 checksummed address
+	NotContractErr     = 1702 //no contract deployed at the sp/jp address
+	OwnerMismatchErr   = 1703 //owner list differs from the multiSignature contract
+	ChainRpcErr        = 1704 //chain can not be reached
+	EffectiveFromErr   = 1705 //effective_from in the past
+	MultiSignExistsErr = 1706 //another revision was saved at the same time
+
+	// NotReadyErr health
+	NotReadyErr = 1801 //mysql or redis unavailable
+	LogLevelErr = 1802 //unknown log level
 
 )
 
@@ -49,6 +96,36 @@ var Msg = map[int]map[int]string{
 		LangZhTw: "token錯誤",
 		LangEn:   "token invalid",
 	},
+	1103: {
+		LangZh:   "没有权限",
+		LangZhTw: "沒有權限",
+		LangEn:   "permission denied",
+	},
+	1104: {
+		LangZh:   "refresh token 无效或已过期",
+		LangZhTw: "refresh token 無效或已過期",
+		LangEn:   "refresh token invalid or expired",
+	},
+	1105: {
+		LangZh:   "会话不存在",
+		LangZhTw: "會話不存在",
+		LangEn:   "session does not exist",
+	},
+	1106: {
+		LangZh:   "签名消息无效",
+		LangZhTw: "簽名消息無效",
+		LangEn:   "sign-in message invalid",
+	},
+	1107: {
+		LangZh:   "签名与地址不匹配",
+		LangZhTw: "簽名與地址不匹配",
+		LangEn:   "signature does not match address",
+	},
+	1108: {
+		LangZh:   "nonce 无效或已使用",
+		LangZhTw: "nonce 無效或已使用",
+		LangEn:   "nonce invalid or already used",
+	},
 	1201: {
 		LangZh:   "sp_name 不能为空",
 		LangZhTw: "sp_name 不能為空",
@@ -79,6 +156,151 @@ var Msg = map[int]map[int]string{
 		LangZhTw: "用戶名或密碼錯誤",
 		LangEn:   "name or password error",
 	},
+	1304: {
+		LangZh:   "管理员已存在",
+		LangZhTw: "管理員已存在",
+		LangEn:   "admin already exists",
+	},
+	1305: {
+		LangZh:   "管理员不存在",
+		LangZhTw: "管理員不存在",
+		LangEn:   "admin does not exist",
+	},
+	1306: {
+		LangZh:   "账号已停用",
+		LangZhTw: "帳號已停用",
+		LangEn:   "account disabled",
+	},
+	1307: {
+		LangZh:   "用户名格式错误",
+		LangZhTw: "用戶名格式錯誤",
+		LangEn:   "name format error",
+	},
+	1308: {
+		LangZh:   "密码格式错误，需为8-64位",
+		LangZhTw: "密碼格式錯誤，需為8-64位",
+		LangEn:   "password must be 8-64 characters",
+	},
+	1309: {
+		LangZh:   "不能停用自己的账号",
+		LangZhTw: "不能停用自己的帳號",
+		LangEn:   "can not disable your own account",
+	},
+	1310: {
+		LangZh:   "角色不存在或不可修改",
+		LangZhTw: "角色不存在或不可修改",
+		LangEn:   "unknown or read-only role",
+	},
+	1311: {
+		LangZh:   "权限不存在",
+		LangZhTw: "權限不存在",
+		LangEn:   "unknown permission",
+	},
+	1312: {
+		LangZh:   "至少需要保留一个超级管理员",
+		LangZhTw: "至少需要保留一個超級管理員",
+		LangEn:   "at least one superadmin must remain",
+	},
+	1401: {
+		LangZh:   "消息格式错误",
+		LangZhTw: "消息格式錯誤",
+		LangEn:   "message format error",
+	},
+	1402: {
+		LangZh:   "未知的订阅主题",
+		LangZhTw: "未知的訂閱主題",
+		LangEn:   "unknown topic",
+	},
+	1403: {
+		LangZh:   "订阅主题数量超过上限",
+		LangZhTw: "訂閱主題數量超過上限",
+		LangEn:   "too many topics",
+	},
+	1404: {
+		LangZh:   "未知的消息类型",
+		LangZhTw: "未知的消息類型",
+		LangEn:   "unknown message type",
+	},
+	1405: {
+		LangZh:   "心跳超时",
+		LangZhTw: "心跳超時",
+		LangEn:   "heartbeat timeout",
+	},
+	1406: {
+		LangZh:   "连接数超过上限",
+		LangZhTw: "連接數超過上限",
+		LangEn:   "too many connections",
+	},
+	1501: {
+		LangZh:   "搜索条件错误",
+		LangZhTw: "搜索條件錯誤",
+		LangEn:   "invalid search filter or sort",
+	},
+	1502: {
+		LangZh:   "分页游标无效",
+		LangZhTw: "分頁游標無效",
+		LangEn:   "invalid cursor",
+	},
+	1601: {
+		LangZh:   "请求过于频繁，请稍后重试",
+		LangZhTw: "請求過於頻繁，請稍後重試",
+		LangEn:   "too many requests, please try again later",
+	},
+	1602: {
+		LangZh:   "API Key 无效或已吊销",
+		LangZhTw: "API Key 無效或已吊銷",
+		LangEn:   "api key invalid or revoked",
+	},
+	1603: {
+		LangZh:   "API Key 不存在",
+		LangZhTw: "API Key 不存在",
+		LangEn:   "api key does not exist",
+	},
+	1604: {
+		LangZh:   "API Key 名称为空或过长",
+		LangZhTw: "API Key 名稱為空或過長",
+		LangEn:   "api key name empty or too long",
+	},
+	1701: {
+		LangZh:   "地址格式错误，需使用 EIP-55 校验和格式",
+		LangZhTw: "地址格式錯誤，需使用 EIP-55 校驗和格式",
+		LangEn:   "address must be EIP-55 checksummed",
+	},
+	1702: {
+		LangZh:   "SP/JP 地址上没有部署合约",
+		LangZhTw: "SP/JP 地址上沒有部署合約",
+		LangEn:   "no contract deployed at sp/jp address",
+	},
+	1703: {
+		LangZh:   "多签账户与链上多签合约不一致",
+		LangZhTw: "多簽賬戶與鏈上多簽合約不一致",
+		LangEn:   "owner list does not match the multi-signature contract",
+	},
+	1704: {
+		LangZh:   "链节点无法访问，请稍后重试",
+		LangZhTw: "鏈節點無法訪問，請稍後重試",
+		LangEn:   "chain rpc unavailable, please try again later",
+	},
+	1705: {
+		LangZh:   "生效时间不能早于当前时间",
+		LangZhTw: "生效時間不能早於當前時間",
+		LangEn:   "effective_from must not be in the past",
+	},
+	1706: {
+		LangZh:   "多签配置已被同时修改，请重试",
+		LangZhTw: "多簽配置已被同時修改，請重試",
+		LangEn:   "multi-sign changed concurrently, please retry",
+	},
+	1801: {
+		LangZh:   "服务未就绪",
+		LangZhTw: "服務未就緒",
+		LangEn:   "service not ready",
+	},
+	1802: {
+		LangZh:   "日志级别错误，可选 debug、info、warn、error",
+		LangZhTw: "日誌級別錯誤，可選 debug、info、warn、error",
+		LangEn:   "log level must be debug, info, warn or error",
+	},
 }
 
 func GetMsg(c int, lang int) string {
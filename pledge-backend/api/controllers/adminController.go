@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models/request"
+	"pledge-backend/api/models/response"
+	"pledge-backend/api/services"
+	"pledge-backend/api/validate"
+)
+
+type AdminController struct {
+}
+
+// List all admin accounts
+func (c *AdminController) List(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+
+	errCode, admins := services.NewAdmin().List()
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	res.Response(ctx, statecode.CommonSuccess, admins)
+}
+
+// Create a new admin account
+func (c *AdminController) Create(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	req := request.CreateAdmin{}
+
+	errCode := validate.NewAdmin().Create(ctx, &req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	errCode = services.NewAdmin().Audit(auditEntry(ctx)).Create(ctx.GetString("role"), &req)
+	res.Response(ctx, errCode, nil)
+}
+
+// SetStatus enable or disable an admin account
+func (c *AdminController) SetStatus(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	req := request.SetAdminStatus{}
+
+	errCode := validate.NewAdmin().SetStatus(ctx, &req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	errCode = services.NewAdmin().Audit(auditEntry(ctx)).SetStatus(ctx.GetString("username"), ctx.GetString("role"), &req)
+	res.Response(ctx, errCode, nil)
+}
+
+// ChangePassword change the password of the logged in admin
+func (c *AdminController) ChangePassword(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	req := request.ChangePassword{}
+
+	errCode := validate.NewAdmin().ChangePassword(ctx, &req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	errCode = services.NewAdmin().Audit(auditEntry(ctx)).ChangePassword(ctx.GetString("username"), &req)
+	res.Response(ctx, errCode, nil)
+}
+
+// ResetPassword set the password of another admin
+func (c *AdminController) ResetPassword(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	req := request.ResetPassword{}
+
+	errCode := validate.NewAdmin().ResetPassword(ctx, &req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	errCode = services.NewAdmin().Audit(auditEntry(ctx)).ResetPassword(ctx.GetString("role"), &req)
+	res.Response(ctx, errCode, nil)
+}
+
+// SetRole change the role of an admin account
+func (c *AdminController) SetRole(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	req := request.SetAdminRole{}
+
+	errCode := validate.NewAdmin().SetRole(ctx, &req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	errCode = services.NewAdmin().Audit(auditEntry(ctx)).SetRole(ctx.GetString("role"), &req)
+	res.Response(ctx, errCode, nil)
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models/request"
+	"pledge-backend/api/models/response"
+	"pledge-backend/api/services"
+	"pledge-backend/api/validate"
+)
+
+type ApiKeyController struct {
+}
+
+// List all api keys, without the keys themselves
+func (c *ApiKeyController) List(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+
+	errCode, keys := services.NewApiKey().List()
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	res.Response(ctx, statecode.CommonSuccess, keys)
+}
+
+// Create issue an api key for an integrator
+func (c *ApiKeyController) Create(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	req := request.CreateApiKey{}
+
+	errCode := validate.NewApiKey().Create(ctx, &req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	errCode, key := services.NewApiKey().Audit(auditEntry(ctx)).Create(ctx.GetString("username"), &req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	res.Response(ctx, statecode.CommonSuccess, key)
+}
+
+// Revoke disable an api key
+func (c *ApiKeyController) Revoke(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	req := request.RevokeApiKey{}
+
+	errCode := validate.NewApiKey().Revoke(ctx, &req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	errCode = services.NewApiKey().Audit(auditEntry(ctx)).Revoke(&req)
+	res.Response(ctx, errCode, nil)
+}
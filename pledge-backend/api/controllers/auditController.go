@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models"
+	"pledge-backend/api/models/request"
+	"pledge-backend/api/models/response"
+	"pledge-backend/api/services"
+	"pledge-backend/api/validate"
+)
+
+type AuditController struct {
+}
+
+// auditEntry audit log entry of the request, nil when the route is not audited
+func auditEntry(ctx *gin.Context) *models.AuditLog {
+	v, _ := ctx.Get(models.AuditContextKey)
+	entry, _ := v.(*models.AuditLog)
+	return entry
+}
+
+// Query audit log by actor, entity and time range
+func (c *AuditController) Query(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	req := request.AuditQuery{}
+
+	errCode := validate.NewAudit().Query(ctx, &req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	errCode, result := services.NewAudit().Query(&req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	res.Response(ctx, statecode.CommonSuccess, result)
+}
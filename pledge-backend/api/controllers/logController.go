@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models/request"
+	"pledge-backend/api/models/response"
+	"pledge-backend/api/services"
+	"pledge-backend/api/validate"
+)
+
+type LogController struct {
+}
+
+// Level current log level
+func (c *LogController) Level(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	res.Response(ctx, statecode.CommonSuccess, services.NewLog().Level())
+}
+
+// SetLevel change the log level at runtime
+func (c *LogController) SetLevel(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	req := request.SetLogLevel{}
+
+	errCode := validate.NewLog().SetLogLevel(ctx, &req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	errCode = services.NewLog().Audit(auditEntry(ctx)).SetLevel(&req)
+	res.Response(ctx, errCode, nil)
+}
@@ -8,6 +8,9 @@ import (
 	"pledge-backend/api/services"
 	"pledge-backend/api/validate"
 	"pledge-backend/log"
+	"pledge-backend/utils"
+
+	"go.uber.org/zap"
 )
 
 type MultiSignPoolController struct {
@@ -16,17 +19,17 @@ type MultiSignPoolController struct {
 func (c *MultiSignPoolController) SetMultiSign(ctx *gin.Context) {
 	res := response.Gin{Res: ctx}
 	req := request.SetMultiSign{}
-	log.Logger.Sugar().Info("SetMultiSign req ", req)
 
 	errCode := validate.NewMutiSign().SetMultiSign(ctx, &req)
 	if errCode != statecode.CommonSuccess {
 		res.Response(ctx, errCode, nil)
 		return
 	}
+	log.FromContext(ctx).Info("SetMultiSign req", zap.Any("req", utils.Redact(req)))
 
-	errCode, err := services.NewMutiSign().SetMultiSign(&req)
+	errCode, err := services.NewMutiSign().Audit(auditEntry(ctx)).SetMultiSign(ctx.Request.Context(), &req, ctx.GetString("username"))
 	if errCode != statecode.CommonSuccess {
-		log.Logger.Error(err.Error())
+		log.FromContext(ctx).Error(err.Error())
 		res.Response(ctx, errCode, nil)
 		return
 	}
@@ -38,7 +41,6 @@ func (c *MultiSignPoolController) GetMultiSign(ctx *gin.Context) {
 	res := response.Gin{Res: ctx}
 	req := request.GetMultiSign{}
 	result := response.MultiSign{}
-	log.Logger.Sugar().Info("GetMultiSign req ", nil)
 
 	errCode := validate.NewMutiSign().GetMultiSign(ctx, &req)
 	if errCode != statecode.CommonSuccess {
@@ -46,9 +48,30 @@ func (c *MultiSignPoolController) GetMultiSign(ctx *gin.Context) {
 		return
 	}
 
-	errCode, err := services.NewMutiSign().GetMultiSign(&result, req.ChainId)
+	errCode, err := services.NewMutiSign().GetMultiSign(ctx.Request.Context(), &result, req.ChainId)
+	if errCode != statecode.CommonSuccess {
+		log.FromContext(ctx).Error(err.Error())
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	res.Response(ctx, statecode.CommonSuccess, result)
+}
+
+// MultiSignHistory every revision of the chain's multi-sign configuration
+func (c *MultiSignPoolController) MultiSignHistory(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	req := request.GetMultiSign{}
+
+	errCode := validate.NewMutiSign().GetMultiSign(ctx, &req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	errCode, result, err := services.NewMutiSign().History(req.ChainId)
 	if errCode != statecode.CommonSuccess {
-		log.Logger.Error(err.Error())
+		log.FromContext(ctx).Error(err.Error())
 		res.Response(ctx, errCode, nil)
 		return
 	}
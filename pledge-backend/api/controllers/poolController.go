@@ -122,7 +122,6 @@ func (c *PoolController) TokenList(ctx *gin.Context) {
 func (c *PoolController) Search(ctx *gin.Context) {
 	res := response.Gin{Res: ctx}
 	req := request.Search{}
-	result := response.Search{}
 
 	// 验证搜索请求参数
 	errCode := validate.NewSearch().Search(ctx, &req)
@@ -132,15 +131,13 @@ func (c *PoolController) Search(ctx *gin.Context) {
 	}
 
 	// 调用服务层进行搜索，返回匹配的池列表和总数
-	errCode, count, pools := services.NewSearch().Search(&req)
+	errCode, result := services.NewSearch().Search(ctx.Request.Context(), &req)
 	if errCode != statecode.CommonSuccess {
 		res.Response(ctx, errCode, nil)
 		return
 	}
 
-	// 封装搜索结果并返回
-	result.Rows = pools
-	result.Count = count
+	// 返回搜索结果
 	res.Response(ctx, statecode.CommonSuccess, result)
 }
 
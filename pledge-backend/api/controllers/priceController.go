@@ -4,6 +4,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"net/http"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models/kucoin"
+	"pledge-backend/api/models/response"
 	"pledge-backend/api/models/ws"
 	"pledge-backend/log"
 	"pledge-backend/utils"
@@ -36,20 +39,56 @@ func (c *PriceController) NewPrice(ctx *gin.Context) {
 		return
 	}
 
-	randomId := ""
-	remoteIP, ok := ctx.RemoteIP()
-	if ok {
-		randomId = strings.Replace(remoteIP.String(), ".", "_", -1) + "_" + utils.GetRandomString(23)
-	} else {
-		randomId = utils.GetRandomString(32)
+	randomId, ip := connIdentity(ctx, "")
+	server := ws.NewServer(randomId, ip, conn)
+
+	go server.ReadAndWrite()
+}
+
+// connIdentity connection id and the client ip the per ip connection limit counts,
+// the socket address unless the request came through one of env.trusted_proxies
+func connIdentity(ctx *gin.Context, prefix string) (string, string) {
+	ip := ctx.ClientIP()
+	if ip == "" {
+		return prefix + utils.GetRandomString(32), ip
 	}
+	return prefix + strings.Replace(ip, ".", "_", -1) + "_" + utils.GetRandomString(23), ip
+}
 
-	server := &ws.Server{
-		Id:       randomId,
-		Socket:   conn,
-		Send:     make(chan []byte, 800),
-		LastTime: time.Now().Unix(),
+// Stream server-sent events of the hub topics, for clients that can not open a websocket
+// topics come from ?topic=price:PLGR-USDT&topic=pool:56:1 or ?topics=a,b, default price:PLGR-USDT
+func (c *PriceController) Stream(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+
+	topics := ctx.QueryArray("topic")
+	for _, t := range strings.Split(ctx.Query("topics"), ",") {
+		if strings.TrimSpace(t) != "" {
+			topics = append(topics, t)
+		}
+	}
+	if len(topics) == 0 {
+		topics = []string{ws.PriceTopic(kucoin.PlgrSymbol)}
 	}
 
-	go server.ReadAndWrite()
+	randomId, ip := connIdentity(ctx, "sse_")
+	stream, code := ws.NewStream(randomId, ip, topics)
+	if code != statecode.CommonSuccess {
+		res.Response(ctx, code, nil, http.StatusBadRequest)
+		return
+	}
+
+	lastId := ws.ParseLastEventId(ctx.Request)
+	code = ws.Manager.Attach(stream, lastId)
+	if code != statecode.CommonSuccess {
+		res.Response(ctx, code, nil, http.StatusServiceUnavailable)
+		return
+	}
+
+	stream.Serve(ctx.Writer, ctx.Request)
+}
+
+// WsMetrics connected websocket clients
+func (c *PriceController) WsMetrics(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	res.Response(ctx, statecode.CommonSuccess, ws.Manager.Snapshot())
 }
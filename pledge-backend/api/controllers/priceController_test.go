@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models/ws"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clientContext a request from addr to an engine trusting no proxy, as env.trusted_proxies = [] does
+func clientContext(t *testing.T, addr string, forwardedFor string) *gin.Context {
+	ctx, engine := gin.CreateTestContext(httptest.NewRecorder())
+	if err := engine.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	ctx.Request = httptest.NewRequest("GET", "/api/v21/price/stream", nil)
+	ctx.Request.RemoteAddr = addr
+	if forwardedFor != "" {
+		ctx.Request.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	return ctx
+}
+
+func TestConnIdentityUsesClientIp(t *testing.T) {
+	id, ip := connIdentity(clientContext(t, "10.1.2.3:5000", "8.8.8.8"), "sse_")
+	if ip != "10.1.2.3" {
+		t.Errorf("ip %q, want the socket address of an untrusted peer", ip)
+	}
+	if len(id) != len("sse_10_1_2_3_")+23 || id[:len("sse_10_1_2_3_")] != "sse_10_1_2_3_" {
+		t.Errorf("unexpected id %q", id)
+	}
+}
+
+func TestConnLimitIsPerClientIp(t *testing.T) {
+	m := ws.NewServerManager()
+	m.MaxConnPerIp = 2
+	go m.Run()
+
+	attach := func(addr string) int {
+		id, ip := connIdentity(clientContext(t, addr, ""), "sse_")
+		st, code := ws.NewStream(id, ip, []string{ws.PriceTopic("PLGR-USDT")})
+		if code != statecode.CommonSuccess {
+			t.Fatalf("new stream %d", code)
+		}
+		return m.Attach(st, ws.EventId{})
+	}
+
+	// more clients than the per ip limit, each from its own address
+	for i := 0; i < 3*m.MaxConnPerIp; i++ {
+		if code := attach(fmt.Sprintf("10.0.0.%d:4000", i+1)); code != statecode.CommonSuccess {
+			t.Fatalf("client %d refused with %d", i, code)
+		}
+	}
+	// the limit still applies to one address
+	for i := 0; i < m.MaxConnPerIp; i++ {
+		if code := attach("10.0.1.1:4000"); code != statecode.CommonSuccess {
+			t.Fatalf("connection %d of 10.0.1.1 refused with %d", i, code)
+		}
+	}
+	if code := attach("10.0.1.1:4001"); code != statecode.WsConnLimitErr {
+		t.Errorf("connection over the per ip limit got %d", code)
+	}
+	if got := m.Snapshot().Streams; got != 4*m.MaxConnPerIp {
+		t.Errorf("%d streams attached, want %d", got, 4*m.MaxConnPerIp)
+	}
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models"
+	"pledge-backend/api/models/request"
+	"pledge-backend/api/models/response"
+	"pledge-backend/api/services"
+	"pledge-backend/api/validate"
+	"pledge-backend/log"
+)
+
+type RbacController struct {
+}
+
+// Roles role to permissions mapping and every known permission
+func (c *RbacController) Roles(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+
+	mapping, err := services.NewRbac().RolePermissions()
+	if err != nil {
+		log.FromContext(ctx).Error(err.Error())
+		res.Response(ctx, statecode.CommonErrServerErr, nil)
+		return
+	}
+
+	res.Response(ctx, statecode.CommonSuccess, response.Roles{
+		Roles:       mapping,
+		Permissions: models.Permissions,
+	})
+}
+
+// SetRolePermissions replace the permissions of a role
+func (c *RbacController) SetRolePermissions(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	req := request.SetRolePermissions{}
+
+	errCode := validate.NewAdmin().SetRolePermissions(ctx, &req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	errCode = services.NewRbac().Audit(auditEntry(ctx)).SetRolePermissions(&req)
+	res.Response(ctx, errCode, nil)
+}
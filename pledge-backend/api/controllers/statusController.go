@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models/response"
+	"pledge-backend/api/services"
+)
+
+type StatusController struct {
+}
+
+// Healthz liveness, the process serves http, no dependency is checked
+func (c *StatusController) Healthz(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	res.Response(ctx, statecode.CommonSuccess, response.Ready{Status: response.StatusOk, Dependencies: []response.Dependency{}})
+}
+
+// Readyz readiness, 503 while mysql or redis is unreachable
+func (c *StatusController) Readyz(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	ready, data := services.NewStatus().Ready()
+	if !ready {
+		res.Response(ctx, statecode.NotReadyErr, data, http.StatusServiceUnavailable)
+		return
+	}
+	res.Response(ctx, statecode.CommonSuccess, data)
+}
+
+// Status every dependency with its latency, scheduler runs, chain sync and price feed staleness
+func (c *StatusController) Status(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	res.Response(ctx, statecode.CommonSuccess, services.NewStatus().Status())
+}
@@ -6,9 +6,11 @@ import (
 	"pledge-backend/api/models/response"
 	"pledge-backend/api/services"
 	"pledge-backend/api/validate"
-	"pledge-backend/db"
+	"pledge-backend/log"
+	"pledge-backend/utils"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 type UserController struct {
@@ -24,8 +26,9 @@ func (c *UserController) Login(ctx *gin.Context) {
 		res.Response(ctx, errCode, nil)
 		return
 	}
+	log.FromContext(ctx).Info("Login req", zap.Any("req", utils.Redact(req)))
 
-	errCode = services.NewUser().Login(&req, &result)
+	errCode = services.NewUser().Audit(auditEntry(ctx)).Login(&req, ctx.ClientIP(), ctx.Request.UserAgent(), &result)
 	if errCode != statecode.CommonSuccess {
 		res.Response(ctx, errCode, nil)
 		return
@@ -34,13 +37,101 @@ func (c *UserController) Login(ctx *gin.Context) {
 	res.Response(ctx, statecode.CommonSuccess, result)
 }
 
+// SiweNonce issue a nonce for a Sign-In with Ethereum message
+func (c *UserController) SiweNonce(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+
+	errCode, nonce := services.NewSiwe().Nonce()
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	res.Response(ctx, statecode.CommonSuccess, nonce)
+}
+
+// SiweLogin sign in with a wallet signature over an EIP-4361 message
+func (c *UserController) SiweLogin(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	req := request.SiweLogin{}
+	result := response.Login{}
+
+	errCode := validate.NewUser().SiweLogin(ctx, &req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	errCode = services.NewSiwe().Audit(auditEntry(ctx)).Login(&req, ctx.ClientIP(), ctx.Request.UserAgent(), &result)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	res.Response(ctx, statecode.CommonSuccess, result)
+}
+
+// Refresh exchange a refresh token for new tokens, the old refresh token stops working
+func (c *UserController) Refresh(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	req := request.Refresh{}
+	result := response.Login{}
+
+	errCode := validate.NewUser().Refresh(ctx, &req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	errCode = services.NewUser().Audit(auditEntry(ctx)).Refresh(&req, &result)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
+
+	res.Response(ctx, statecode.CommonSuccess, result)
+}
+
+// Logout end the current session
 func (c *UserController) Logout(ctx *gin.Context) {
 	res := response.Gin{Res: ctx}
 
-	usernameIntf, _ := ctx.Get("username")
+	errCode := services.NewUser().Audit(auditEntry(ctx)).RevokeSession(ctx.GetString("username"), ctx.GetString("session_id"))
+	res.Response(ctx, errCode, nil)
+}
+
+// LogoutAll end every session of the logged in admin
+func (c *UserController) LogoutAll(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+
+	errCode := services.NewUser().Audit(auditEntry(ctx)).LogoutAll(ctx.GetString("username"))
+	res.Response(ctx, errCode, nil)
+}
+
+// Sessions list sessions of the logged in admin
+func (c *UserController) Sessions(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+
+	errCode, sessions := services.NewUser().Sessions(ctx.GetString("username"), ctx.GetString("session_id"))
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
 
-	//delete username in redis
-	_, _ = db.RedisDelete(usernameIntf.(string))
+	res.Response(ctx, statecode.CommonSuccess, sessions)
+}
+
+// RevokeSession end one session of the logged in admin
+func (c *UserController) RevokeSession(ctx *gin.Context) {
+	res := response.Gin{Res: ctx}
+	req := request.RevokeSession{}
+
+	errCode := validate.NewUser().RevokeSession(ctx, &req)
+	if errCode != statecode.CommonSuccess {
+		res.Response(ctx, errCode, nil)
+		return
+	}
 
-	res.Response(ctx, statecode.CommonSuccess, nil)
+	errCode = services.NewUser().Audit(auditEntry(ctx)).RevokeSession(ctx.GetString("username"), req.SessionId)
+	res.Response(ctx, errCode, nil)
 }
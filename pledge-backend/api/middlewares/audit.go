@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"pledge-backend/api/models"
+	"pledge-backend/log"
+	"pledge-backend/utils"
+	"strings"
+	"time"
+)
+
+// auditBodyLimit request bytes kept in the audit log
+const auditBodyLimit = 64 << 10
+
+// auditSkip POST routes that only read
+var auditSkip = []string{"/pool/getMultiSign", "/pool/multiSignHistory"}
+
+// Audit write an audit_log entry for every request that is not a read, after it was handled.
+// Services add the changed entity through the entry stored under models.AuditContextKey.
+func Audit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions || auditSkipped(c.FullPath()) {
+			c.Next()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = ioutil.ReadAll(io.LimitReader(c.Request.Body, auditBodyLimit+1))
+			c.Request.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+			if len(body) > auditBodyLimit {
+				body = nil
+			}
+		}
+		entry := &models.AuditLog{
+			Ip:          c.ClientIP(), // socket address unless the request came through one of env.trusted_proxies
+			Method:      method,
+			Route:       c.FullPath(),
+			RequestBody: utils.RedactBody(body, c.ContentType()),
+			CreatedAt:   time.Now(),
+		}
+		c.Set(models.AuditContextKey, entry)
+
+		c.Next()
+
+		if username := c.GetString("username"); username != "" {
+			entry.Actor = username
+			entry.ActorRole = c.GetString("role")
+		}
+		entry.ResultCode = c.GetInt("response_code")
+		entry.HttpStatus = c.Writer.Status()
+		if err := entry.Create(); err != nil {
+			log.Logger.Sugar().Error("audit log write err ", err)
+		}
+	}
+}
+
+func auditSkipped(route string) bool {
+	for _, suffix := range auditSkip {
+		if strings.HasSuffix(route, suffix) {
+			return true
+		}
+	}
+	return false
+}
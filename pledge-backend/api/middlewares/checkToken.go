@@ -3,9 +3,10 @@ package middlewares
 import (
 	"github.com/gin-gonic/gin"
 	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models"
 	"pledge-backend/api/models/response"
+	"pledge-backend/api/services"
 	"pledge-backend/config"
-	"pledge-backend/db"
 	"pledge-backend/utils"
 )
 
@@ -14,28 +15,34 @@ func CheckToken() gin.HandlerFunc {
 		res := response.Gin{Res: c}
 		token := c.Request.Header.Get("authCode")
 
-		username, err := utils.ParseToken(token, config.Config.Jwt.SecretKey)
+		claims, err := utils.ParseTokenClaims(token, config.Config.Jwt.SecretKey)
 		if err != nil {
 			res.Response(c, statecode.TokenErr, nil)
 			c.Abort()
 			return
 		}
+		username := claims.Username
 
-		if username != config.Config.DefaultAdmin.Username {
+		// a token issued before a role change is no longer valid
+		if !services.NewUser().Authorized(claims) {
 			res.Response(c, statecode.TokenErr, nil)
 			c.Abort()
 			return
 		}
 
-		// Judge whether the user logout
-		resByteArr, err := db.RedisGet(username)
-		if string(resByteArr) != `"login_ok"` {
+		// Judge whether the session was logged out or revoked
+		session := models.NewSession()
+		if err = session.Get(claims.SessionId); err != nil || session.Username != username {
 			res.Response(c, statecode.TokenErr, nil)
 			c.Abort()
 			return
 		}
 
 		c.Set("username", username)
+		c.Set("role", claims.Role)
+		c.Set("permissions", claims.Permissions)
+		c.Set("session_id", claims.SessionId)
+		c.Set("address", claims.Address)
 
 		c.Next()
 	}
@@ -3,19 +3,25 @@ package middlewares
 import (
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"pledge-backend/config"
+	"pledge-backend/utils"
 )
 
-// Cors 跨域中间件
+// Cors 跨域中间件，允许的来源取自 [cors] allow_origins，可热更新
 func Cors() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		method := c.Request.Method
 		origin := c.Request.Header.Get("Origin")
 
-		if origin != "" {
-			c.Header("Access-Control-Allow-Origin", "*")
+		allowOrigin := corsOrigin(origin)
+		if origin != "" && allowOrigin != "*" {
+			c.Header("Vary", "Origin")
+		}
+		if allowOrigin != "" {
+			c.Header("Access-Control-Allow-Origin", allowOrigin)
 			c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, UPDATE")
-			c.Header("Access-Control-Allow-Headers", "Origin, X-Requested-With, authCode, token, Content-Type, Accept, Authorization")
-			c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Cache-Control, Content-Language, Content-Type")
+			c.Header("Access-Control-Allow-Headers", "Origin, X-Requested-With, authCode, token, Content-Type, Accept, Authorization, Last-Event-ID, X-API-Key, X-Request-ID, traceparent, tracestate")
+			c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Cache-Control, Content-Language, Content-Type, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After, X-Request-ID, X-Trace-Id")
 			c.Header("Access-Control-Allow-Credentials", "false")
 			c.Set("content-type", "application/json")
 		}
@@ -25,3 +31,18 @@ func Cors() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// corsOrigin value of Access-Control-Allow-Origin for origin, "" when it is not allowed
+func corsOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	allowOrigins := config.Current().Cors.AllowOrigins
+	if utils.IsContain("*", allowOrigins) {
+		return "*"
+	}
+	if utils.IsContain(origin, allowOrigins) {
+		return origin
+	}
+	return ""
+}
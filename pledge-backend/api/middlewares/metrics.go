@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"pledge-backend/metrics"
+	"strconv"
+	"time"
+)
+
+// Metrics count and time every request by its route template, requests no route matched share one label
+// so scanners cannot blow up the series count
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HttpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HttpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,89 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models/response"
+	"pledge-backend/api/services"
+	"pledge-backend/config"
+	"pledge-backend/db"
+	"pledge-backend/log"
+	"strconv"
+)
+
+// route groups with their own quota in [ratelimit.groups]
+const (
+	RateLimitPublic = "public"
+	RateLimitSearch = "search"
+	RateLimitAuth   = "auth"
+	RateLimitAdmin  = "admin"
+)
+
+// RateLimit token bucket per api key (X-API-Key header) or else per client ip, shared by all instances through redis.
+// Requests are let through when redis is unavailable.
+func RateLimit(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conf := config.Current().RateLimit
+		quota, ok := conf.Groups[group]
+		if !conf.Enabled || !ok {
+			c.Next()
+			return
+		}
+		res := response.Gin{Res: c}
+
+		subject := "ip:" + c.ClientIP()
+		rate, burst := quota.IpRate, quota.IpBurst
+		if plain := c.GetHeader("X-API-Key"); plain != "" {
+			apiKey := services.NewApiKey()
+			// a key that is not cached costs a database lookup, charge the ip for it first so made up keys
+			// can not get around the ip quota
+			if !apiKey.Known(plain) && !take(c, group, subject, rate, burst) {
+				return
+			}
+			key, code := apiKey.Lookup(plain)
+			if code == statecode.ApiKeyErr {
+				res.Response(c, code, nil, http.StatusUnauthorized)
+				c.Abort()
+				return
+			}
+			if code != statecode.CommonSuccess {
+				res.Response(c, code, nil)
+				c.Abort()
+				return
+			}
+			subject = "key:" + strconv.Itoa(key.Id)
+			rate, burst = quota.KeyRate, quota.KeyBurst
+			c.Set("api_key_id", key.Id)
+		}
+		if !take(c, group, subject, rate, burst) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// take a token from the bucket of subject, false when the request was rejected with 429
+func take(c *gin.Context, group string, subject string, rate float64, burst int) bool {
+	if rate <= 0 || burst <= 0 {
+		return true
+	}
+	allowed, remaining, err := db.RedisTokenBucket(c.Request.Context(), "ratelimit:"+group+":"+subject, rate, burst)
+	if err != nil {
+		log.Logger.Error("rate limit " + err.Error())
+		return true
+	}
+	c.Header("X-RateLimit-Limit", strconv.Itoa(burst))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(int(math.Floor(remaining))))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil((float64(burst)-remaining)/rate)))) // s until the bucket is full
+	if !allowed {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil((1-remaining)/rate))))
+		res := response.Gin{Res: c}
+		res.Response(c, statecode.RateLimitErr, nil, http.StatusTooManyRequests)
+		c.Abort()
+		return false
+	}
+	return true
+}
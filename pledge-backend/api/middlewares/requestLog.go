@@ -0,0 +1,89 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/url"
+	"pledge-backend/log"
+	"pledge-backend/tracing"
+	"pledge-backend/utils"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequestIdHeader carries the request id in both directions
+const RequestIdHeader = "X-Request-ID"
+
+// requestIdPattern ids accepted from a client or proxy, anything else is replaced so it can not forge log lines
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestLog assign every request an id, reusing a valid X-Request-ID, and give it a logger carrying
+// the id, route and ip, see log.FromContext. Writes one access log line once the request is handled
+func RequestLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestId := c.GetHeader(RequestIdHeader)
+		if !requestIdPattern.MatchString(requestId) {
+			requestId, _ = utils.RandomHex(16)
+		}
+		c.Header(RequestIdHeader, requestId)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		fields := []zap.Field{
+			zap.String("request_id", requestId),
+			zap.String("route", route),
+			zap.String("ip", c.ClientIP()),
+		}
+		if traceId := tracing.TraceId(c.Request.Context()); traceId != "" {
+			fields = append(fields, zap.String("trace_id", traceId))
+		}
+		logger := log.Logger.With(fields...)
+		c.Set(log.ContextKey, logger)
+		c.Request = c.Request.WithContext(log.WithContext(c.Request.Context(), logger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		access := []zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.String("query", redactQuery(c.Request.URL.RawQuery)),
+			zap.Int("status", status),
+			zap.Int("code", c.GetInt("response_code")),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("bytes", c.Writer.Size()),
+			zap.String("user_agent", c.Request.UserAgent()),
+		}
+		if username := c.GetString("username"); username != "" {
+			access = append(access, zap.String("user", username))
+		}
+		if status >= http.StatusInternalServerError {
+			logger.Error("access", access...)
+		} else {
+			logger.Info("access", access...)
+		}
+	}
+}
+
+// redactQuery mask sensitive query parameters, e.g. a token passed to a websocket or sse endpoint
+func redactQuery(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return utils.Redacted
+	}
+	for k := range values {
+		if utils.IsSensitiveKey(k) {
+			values[k] = []string{utils.Redacted}
+		}
+	}
+	return values.Encode()
+}
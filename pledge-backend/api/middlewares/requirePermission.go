@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models/response"
+	"pledge-backend/api/services"
+	"pledge-backend/utils"
+)
+
+// RequirePermission must run after CheckToken, the permission has to be in the token
+// and still granted to the role (mapping edits take effect without logging in again)
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res := response.Gin{Res: c}
+
+		permissions := c.GetStringSlice("permissions")
+		if !utils.IsContain(permission, permissions) || !services.NewRbac().HasPermission(c.GetString("role"), permission) {
+			res.Response(c, statecode.PermissionErr, nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"pledge-backend/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace a server span per request, continuing the trace of an incoming traceparent header.
+// The span travels in c.Request.Context(), sql, redis and rpc calls made with it become its children
+func Trace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := tracing.Start(ctx, c.Request.Method+" "+route, trace.SpanKindServer,
+			semconv.HTTPMethodKey.String(c.Request.Method),
+			semconv.HTTPRouteKey.String(route),
+			semconv.HTTPTargetKey.String(c.Request.URL.RequestURI()),
+			semconv.HTTPClientIPKey.String(c.ClientIP()),
+		)
+		c.Request = c.Request.WithContext(ctx)
+		if traceId := tracing.TraceId(ctx); traceId != "" {
+			c.Header("X-Trace-Id", traceId)
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+		// client errors are answers, only 5xx marks the span failed
+		var err error
+		if status >= http.StatusInternalServerError {
+			err = fmt.Errorf("%d %s", status, http.StatusText(status))
+			if len(c.Errors) > 0 {
+				err = c.Errors.Last().Err
+			}
+		}
+		tracing.End(span, err)
+	}
+}
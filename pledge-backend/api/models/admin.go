@@ -0,0 +1,106 @@
+package models
+
+import (
+	"errors"
+	"pledge-backend/db"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// admin status
+const (
+	AdminDisabled = 0
+	AdminEnabled  = 1
+)
+
+// Admin 后台管理员账号，密码只保存 bcrypt 哈希
+type Admin struct {
+	UserId    int       `json:"user_id" gorm:"column:user_id;primaryKey;autoIncrement"`
+	Name      string    `json:"name" gorm:"column:name;size:100;not null;uniqueIndex"`
+	Password  string    `json:"-" gorm:"column:password;size:100;not null"`
+	Status    int       `json:"status" gorm:"column:status;not null;default:1"`
+	Role      string    `json:"role" gorm:"column:role;size:32;not null;default:viewer"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func NewAdmin() *Admin {
+	return &Admin{}
+}
+
+func (a *Admin) TableName() string {
+	return "admin"
+}
+
+// Enabled whether the account may log in
+func (a *Admin) Enabled() bool {
+	return a.Status == AdminEnabled
+}
+
+// GetByName load the admin into a, gorm.ErrRecordNotFound when it does not exist
+func (a *Admin) GetByName(name string) error {
+	return db.Mysql.Table("admin").Where("name = ?", name).First(a).Debug().Error
+}
+
+// Exists whether an admin named name exists
+func (a *Admin) Exists(name string) (bool, error) {
+	err := NewAdmin().GetByName(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Count number of admins
+func (a *Admin) Count() (int64, error) {
+	var total int64
+	err := db.Mysql.Table("admin").Count(&total).Error
+	return total, err
+}
+
+// List all admins ordered by id
+func (a *Admin) List() ([]Admin, error) {
+	admins := []Admin{}
+	err := db.Mysql.Table("admin").Order("user_id asc").Find(&admins).Debug().Error
+	return admins, err
+}
+
+// CountRole number of admins having role
+func (a *Admin) CountRole(role string) (int64, error) {
+	var total int64
+	err := db.Mysql.Table("admin").Where("role = ?", role).Count(&total).Error
+	return total, err
+}
+
+// First the admin with the lowest id
+func (a *Admin) First() error {
+	return db.Mysql.Table("admin").Order("user_id asc").First(a).Debug().Error
+}
+
+// Create insert an admin, passwordHash must already be hashed
+func (a *Admin) Create(name string, passwordHash string, role string) error {
+	a.Name = name
+	a.Password = passwordHash
+	a.Status = AdminEnabled
+	a.Role = role
+	return db.Mysql.Table("admin").Create(a).Debug().Error
+}
+
+// SetStatus enable or disable the admin
+func (a *Admin) SetStatus(name string, status int) error {
+	return db.Mysql.Table("admin").Where("name = ?", name).
+		Updates(map[string]interface{}{"status": status, "updated_at": time.Now()}).Debug().Error
+}
+
+// SetRole change the role of the admin
+func (a *Admin) SetRole(name string, role string) error {
+	return db.Mysql.Table("admin").Where("name = ?", name).
+		Updates(map[string]interface{}{"role": role, "updated_at": time.Now()}).Debug().Error
+}
+
+// SetPassword replace the password hash of the admin
+func (a *Admin) SetPassword(name string, passwordHash string) error {
+	return db.Mysql.Table("admin").Where("name = ?", name).
+		Updates(map[string]interface{}{"password": passwordHash, "updated_at": time.Now()}).Debug().Error
+}
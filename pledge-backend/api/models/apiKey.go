@@ -0,0 +1,70 @@
+package models
+
+import (
+	"pledge-backend/db"
+	"time"
+)
+
+// api key status
+const (
+	ApiKeyRevoked = 0
+	ApiKeyActive  = 1
+)
+
+// ApiKey 对接方的 API Key，只保存 sha256，明文仅在创建时返回一次
+type ApiKey struct {
+	Id        int        `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Name      string     `json:"name" gorm:"column:name;size:100;not null"`
+	Prefix    string     `json:"prefix" gorm:"column:prefix;size:16;not null"` // first characters of the key, to recognise it
+	KeyHash   string     `json:"-" gorm:"column:key_hash;size:64;not null;uniqueIndex"`
+	Status    int        `json:"status" gorm:"column:status;not null;default:1"`
+	CreatedBy string     `json:"created_by" gorm:"column:created_by;size:100"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at"`
+	RevokedAt *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+}
+
+func NewApiKey() *ApiKey {
+	return &ApiKey{}
+}
+
+func (k *ApiKey) TableName() string {
+	return "api_key"
+}
+
+// Active whether the key may be used
+func (k *ApiKey) Active() bool {
+	return k.Status == ApiKeyActive
+}
+
+// GetByHash load the key into k, gorm.ErrRecordNotFound when it does not exist
+func (k *ApiKey) GetByHash(hash string) error {
+	return db.Mysql.Table("api_key").Where("key_hash = ?", hash).First(k).Error
+}
+
+// GetById load the key into k, gorm.ErrRecordNotFound when it does not exist
+func (k *ApiKey) GetById(id int) error {
+	return db.Mysql.Table("api_key").Where("id = ?", id).First(k).Debug().Error
+}
+
+// List all keys, newest first
+func (k *ApiKey) List() ([]ApiKey, error) {
+	keys := []ApiKey{}
+	err := db.Mysql.Table("api_key").Order("id desc").Find(&keys).Debug().Error
+	return keys, err
+}
+
+// Create insert a key, hash is the sha256 of the key
+func (k *ApiKey) Create(name string, prefix string, hash string, createdBy string) error {
+	k.Name = name
+	k.Prefix = prefix
+	k.KeyHash = hash
+	k.Status = ApiKeyActive
+	k.CreatedBy = createdBy
+	return db.Mysql.Table("api_key").Create(k).Debug().Error
+}
+
+// Revoke disable key id
+func (k *ApiKey) Revoke(id int) error {
+	return db.Mysql.Table("api_key").Where("id = ?", id).
+		Updates(map[string]interface{}{"status": ApiKeyRevoked, "revoked_at": time.Now()}).Debug().Error
+}
@@ -0,0 +1,185 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"pledge-backend/config"
+	abifile "pledge-backend/contract/abi"
+	"pledge-backend/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// callTimeout upper bound of one rpc round trip
+const callTimeout = 10 * time.Second
+
+// maxSignatureOwners signatureOwners(i) is read until it reverts, at most this many
+const maxSignatureOwners = 64
+
+// ownersTTL how long a signer set read from the chain is reused, an owner change on chain shows up within it
+const ownersTTL = time.Minute
+
+type ownersEntry struct {
+	owners    []common.Address
+	threshold int64
+	loadedAt  time.Time
+}
+
+var ownersLock sync.Mutex
+var ownersCache = map[string]ownersEntry{} // chain id:multi-signature address
+
+// ErrUnknownChain chain id not configured in [testnet] or [mainnet]
+var ErrUnknownChain = errors.New("unknown chain")
+
+// Net rpc url, pledge pool and multi-signature contract configured for chainId
+type Net struct {
+	NetUrl         string
+	PledgePool     string
+	MultiSignature string
+}
+
+// GetNet configuration of chainId
+func GetNet(chainId int) (Net, error) {
+	id := strconv.Itoa(chainId)
+	switch id {
+	case config.Config.TestNet.ChainId:
+		return Net{config.Config.TestNet.NetUrl, config.Config.TestNet.PledgePoolToken, config.Config.TestNet.MultiSignatureAddress}, nil
+	case config.Config.MainNet.ChainId:
+		return Net{config.Config.MainNet.NetUrl, config.Config.MainNet.PledgePoolToken, config.Config.MainNet.MultiSignatureAddress}, nil
+	}
+	return Net{}, ErrUnknownChain
+}
+
+// Client rpc connection to chainId, close it when done
+type Client struct {
+	*ethclient.Client
+	chainId int
+	net     Net
+}
+
+func Dial(ctx context.Context, chainId int) (*Client, error) {
+	net, err := GetNet(chainId)
+	if err != nil {
+		return nil, err
+	}
+	c, err := metrics.DialEth(ctx, strconv.Itoa(chainId), net.NetUrl)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Client: c, chainId: chainId, net: net}, nil
+}
+
+// IsContract whether code is deployed at address
+func (c *Client) IsContract(ctx context.Context, address string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+	code, err := c.CodeAt(ctx, common.HexToAddress(address), nil)
+	if err != nil {
+		return false, err
+	}
+	return len(code) > 0, nil
+}
+
+func (c *Client) multiSignatureContract(address common.Address) (*bind.BoundContract, error) {
+	abiStr, err := abifile.GetAbiByToken("multi_signature")
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := abi.JSON(strings.NewReader(abiStr))
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, parsed, c, c, c), nil
+}
+
+func (c *Client) call(ctx context.Context, contract *bind.BoundContract, method string, args ...interface{}) ([]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+	out := []interface{}{}
+	err := contract.Call(&bind.CallOpts{Context: ctx}, &out, method, args...)
+	return out, err
+}
+
+// MultiSignatureAddress the configured multi-signature contract, or the one the pledge pool points at
+func (c *Client) MultiSignatureAddress(ctx context.Context) (common.Address, error) {
+	if c.net.MultiSignature != "" {
+		return common.HexToAddress(c.net.MultiSignature), nil
+	}
+	pool, err := c.multiSignatureContract(common.HexToAddress(c.net.PledgePool))
+	if err != nil {
+		return common.Address{}, err
+	}
+	out, err := c.call(ctx, pool, "getMultiSignatureAddress")
+	if err != nil {
+		return common.Address{}, err
+	}
+	return *abi.ConvertType(out[0], new(common.Address)).(*common.Address), nil
+}
+
+// SignatureOwners signer set and threshold of the multi-signature contract, cached for ownersTTL
+func (c *Client) SignatureOwners(ctx context.Context) ([]common.Address, int64, error) {
+	address, err := c.MultiSignatureAddress(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	key := strconv.Itoa(c.chainId) + ":" + address.Hex()
+	ownersLock.Lock()
+	entry, ok := ownersCache[key]
+	ownersLock.Unlock()
+	if ok && time.Since(entry.loadedAt) < ownersTTL {
+		return entry.owners, entry.threshold, nil
+	}
+
+	contract, err := c.multiSignatureContract(address)
+	if err != nil {
+		return nil, 0, err
+	}
+	out, err := c.call(ctx, contract, "threshold")
+	if err != nil {
+		return nil, 0, err
+	}
+	threshold := abi.ConvertType(out[0], new(big.Int)).(*big.Int).Int64()
+
+	// the contract has no length getter, read until the index is past the end. A set read with any other error
+	// is incomplete, it is returned as the error and never cached
+	owners := []common.Address{}
+	for i := 0; i < maxSignatureOwners; i++ {
+		out, err = c.call(ctx, contract, "signatureOwners", big.NewInt(int64(i)))
+		if err != nil {
+			if pastEnd(err) {
+				break
+			}
+			return nil, 0, err
+		}
+		owners = append(owners, *abi.ConvertType(out[0], new(common.Address)).(*common.Address))
+	}
+
+	ownersLock.Lock()
+	ownersCache[key] = ownersEntry{owners: owners, threshold: threshold, loadedAt: time.Now()}
+	ownersLock.Unlock()
+	return owners, threshold, nil
+}
+
+// pastEnd whether a call failed because the evm stopped it, as reading past the end of an array does:
+// solidity >= 0.8 reverts with Panic(0x32) (code 3), older contracts hit an invalid opcode. Rate limits, missing
+// trie nodes and other node failures are not the end, the caller gets them
+func pastEnd(err error) bool {
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	if rpcErr.ErrorCode() == 3 {
+		return true
+	}
+	msg := rpcErr.Error()
+	return strings.HasPrefix(msg, "execution reverted") || strings.HasPrefix(msg, "invalid opcode")
+}
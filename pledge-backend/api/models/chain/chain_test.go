@@ -0,0 +1,38 @@
+package chain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// rpcError a json-rpc error response as the node returns it
+type rpcError struct {
+	code int
+	msg  string
+}
+
+func (e rpcError) Error() string  { return e.msg }
+func (e rpcError) ErrorCode() int { return e.code }
+
+func TestPastEnd(t *testing.T) {
+	for _, c := range []struct {
+		err  error
+		want bool
+	}{
+		{rpcError{3, "execution reverted"}, true},
+		{rpcError{-32000, "execution reverted"}, true},
+		{rpcError{-32000, "invalid opcode: INVALID"}, true},
+		{fmt.Errorf("call: %w", rpcError{3, "execution reverted"}), true},
+		{rpcError{-32000, "header not found"}, false},
+		{rpcError{-32000, "missing trie node 1f0c… (path )"}, false},
+		{rpcError{-32005, "limit exceeded"}, false},
+		{rpcError{-32603, "internal error"}, false},
+		{errors.New("execution reverted"), false}, // not an answer from the node
+		{errors.New("context deadline exceeded"), false},
+	} {
+		if got := pastEnd(c.err); got != c.want {
+			t.Errorf("pastEnd(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Column a queryable column. Only this package can create one, so request data can
+// never end up in the sql text, it is always bound as a parameter.
+type Column struct {
+	name    string
+	expr    string
+	numeric bool // stored as varchar, compared as a decimal
+}
+
+func column(name string) Column {
+	return Column{name: name, expr: name}
+}
+
+// tableColumn a column qualified with its table, needed once the query joins
+func tableColumn(table string, name string) Column {
+	return Column{name: name, expr: table + "." + name}
+}
+
+// numeric columns are stored as varchar
+func numericColumn(table string, name string) Column {
+	return Column{
+		name:    name,
+		expr:    fmt.Sprintf("CAST(COALESCE(NULLIF(%s.%s,''),'0') AS DECIMAL(65,0))", table, name),
+		numeric: true,
+	}
+}
+
+// Name column name, used to tell filters apart
+func (c Column) Name() string {
+	return c.name
+}
+
+func (c Column) placeholder() string {
+	if c.numeric {
+		return "CAST(? AS DECIMAL(65,10))"
+	}
+	return "?"
+}
+
+// ColChainId chain_id of single table queries
+var ColChainId = column("chain_id")
+
+// Filter one parameterized condition, built by Eq, Gte, Lte, In or After
+type Filter struct {
+	column Column
+	sql    string
+	args   []interface{}
+}
+
+// Column the column the filter applies to
+func (f Filter) Column() Column {
+	return f.column
+}
+
+// Scope the filter as a gorm scope
+func (f Filter) Scope() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(f.sql, f.args...)
+	}
+}
+
+func compare(c Column, op string, v interface{}) Filter {
+	return Filter{column: c, sql: c.expr + " " + op + " " + c.placeholder(), args: []interface{}{v}}
+}
+
+// Eq column = v
+func Eq(c Column, v interface{}) Filter {
+	return compare(c, "=", v)
+}
+
+// Gte column >= v
+func Gte(c Column, v interface{}) Filter {
+	return compare(c, ">=", v)
+}
+
+// Lte column <= v
+func Lte(c Column, v interface{}) Filter {
+	return compare(c, "<=", v)
+}
+
+// In column in (values)
+func In(c Column, values []string) Filter {
+	args := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		args = append(args, v)
+	}
+	return Filter{column: c, sql: c.expr + " IN ?", args: []interface{}{args}}
+}
+
+// Filters conditions combined with and
+type Filters []Filter
+
+// Without the filters not applying to c, used by facets
+func (fs Filters) Without(c Column) Filters {
+	res := make(Filters, 0, len(fs))
+	for _, f := range fs {
+		if f.column.name != c.name {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// Scopes the filters as gorm scopes, db.Scopes(filters.Scopes()...)
+func (fs Filters) Scopes() []func(*gorm.DB) *gorm.DB {
+	scopes := make([]func(*gorm.DB) *gorm.DB, 0, len(fs))
+	for _, f := range fs {
+		scopes = append(scopes, f.Scope())
+	}
+	return scopes
+}
+
+// Order one sort key
+type Order struct {
+	Column Column
+	Desc   bool
+}
+
+// Scope the order as a gorm scope
+func (o Order) Scope() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(clause.OrderByColumn{Column: clause.Column{Name: o.Column.expr, Raw: true}, Desc: o.Desc})
+	}
+}
+
+// OrderBy the orders as gorm scopes
+func OrderBy(orders ...Order) []func(*gorm.DB) *gorm.DB {
+	scopes := make([]func(*gorm.DB) *gorm.DB, 0, len(orders))
+	for _, o := range orders {
+		scopes = append(scopes, o.Scope())
+	}
+	return scopes
+}
+
+// After rows strictly after keys in the given order (keyset pagination), keys are the
+// values of the order columns of the last row already returned. Order columns must be numeric.
+func After(orders []Order, keys []string) Filter {
+	ors := make([]string, 0, len(orders))
+	args := make([]interface{}, 0)
+	for i, o := range orders {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, orders[j].Column.expr+" = CAST(? AS DECIMAL(65,10))")
+			args = append(args, keys[j])
+		}
+		op := ">"
+		if o.Desc {
+			op = "<"
+		}
+		ands = append(ands, o.Column.expr+" "+op+" CAST(? AS DECIMAL(65,10))")
+		args = append(args, keys[i])
+		ors = append(ors, "("+strings.Join(ands, " and ")+")")
+	}
+	return Filter{sql: "(" + strings.Join(ors, " or ") + ")", args: args}
+}
+
+// WithContext run the query under ctx, its sql is traced below the request span
+func WithContext(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db.Statement.Context = ctx
+		return db
+	}
+}
+
+// Paginate offset pagination, page starts at 1
+func Paginate(page int, pageSize int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if page > 1 {
+			db = db.Offset((page - 1) * pageSize)
+		}
+		return db.Limit(pageSize)
+	}
+}
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"pledge-backend/api/models/request"
+	"pledge-backend/db"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// hostile values a client may send, none of them may reach the sql text
+var hostile = []string{
+	"' OR 1=1 --",
+	"1); DROP TABLE poolbases; --",
+	"BUSD` --",
+	"\\' UNION SELECT password FROM admin_users #",
+	"1 AND SLEEP(5)",
+}
+
+// dryRun a mysql session that only builds statements
+func dryRun(t *testing.T) {
+	t.Helper()
+	conn, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "pledge:pledge@tcp(127.0.0.1:3306)/pledge?charset=utf8mb4&parseTime=true",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := db.Mysql
+	db.Mysql = conn
+	t.Cleanup(func() { db.Mysql = prev })
+}
+
+// searchSql the statement /pool/search runs for filters and orders, with its bound values
+func searchSql(filters Filters, orders []Order) (string, []interface{}) {
+	stmt := poolViewQuery(orders).Scopes(filters.Scopes()...).Scopes(Paginate(2, 10)).Find(&[]PoolView{}).Statement
+	return stmt.SQL.String(), stmt.Vars
+}
+
+// flatten vars, In binds its values as one slice
+func flatten(vars []interface{}) []string {
+	res := make([]string, 0, len(vars))
+	for _, v := range vars {
+		if list, ok := v.([]interface{}); ok {
+			res = append(res, flatten(list)...)
+			continue
+		}
+		res = append(res, fmt.Sprint(v))
+	}
+	return res
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func searchRequest(value string) *request.Search {
+	return &request.Search{
+		ChainID:           97,
+		LendTokenSymbol:   value,
+		BorrowTokenSymbol: value,
+		State:             value,
+		InterestRateMin:   value,
+		InterestRateMax:   value,
+		MartgageRateMin:   value,
+		MartgageRateMax:   value,
+	}
+}
+
+func TestPoolSearchFiltersBindValues(t *testing.T) {
+	dryRun(t)
+	orders, _ := ParsePoolSorts(nil)
+	want, _ := searchSql(PoolSearchFilters(searchRequest("BUSD")), orders)
+
+	for _, value := range hostile {
+		sql, vars := searchSql(PoolSearchFilters(searchRequest(value)), orders)
+		if sql != want {
+			t.Errorf("%q changed the sql\n got %s\nwant %s", value, sql, want)
+		}
+		if strings.Contains(sql, value) {
+			t.Errorf("%q is in the sql text: %s", value, sql)
+		}
+		if got := flatten(vars); !contains(got, value) {
+			t.Errorf("%q is not bound, vars %v", value, got)
+		}
+	}
+}
+
+func TestInBindsValues(t *testing.T) {
+	dryRun(t)
+	orders, _ := ParsePoolSorts(nil)
+	want, _ := searchSql(Filters{In(ColState, []string{"0", "1"})}, orders)
+
+	sql, vars := searchSql(Filters{In(ColState, hostile[:2])}, orders)
+	if sql != want {
+		t.Errorf("in changed the sql\n got %s\nwant %s", sql, want)
+	}
+	for _, value := range hostile[:2] {
+		if strings.Contains(sql, value) || !contains(flatten(vars), value) {
+			t.Errorf("%q is not bound: %s %v", value, sql, vars)
+		}
+	}
+}
+
+func TestParsePoolSortsRejectsUnknown(t *testing.T) {
+	for _, spec := range [][]string{
+		{"interestRate; DROP TABLE poolbases"},
+		{"poolbases.pool_id"},
+		{"interest_rate"},
+		{"(SELECT 1)"},
+		{"interestRate:desc --"},
+		{"interestRate:desc, endTime"},
+		{"interestRate:sideways"},
+		{"interestRate", "interestRate:desc"},
+		{"interestRate", "endTime", "settleTime", "maxSupply"},
+	} {
+		if orders, ok := ParsePoolSorts(spec); ok {
+			t.Errorf("%q accepted as %+v", spec, orders)
+		}
+	}
+}
+
+func TestParsePoolSortsOrderSql(t *testing.T) {
+	dryRun(t)
+	orders, ok := ParsePoolSorts([]string{"interestRate:desc", "endTime"})
+	if !ok {
+		t.Fatal("valid sort rejected")
+	}
+	if len(orders) != 3 || orders[2].Column.Name() != "pool_id" || orders[2].Desc {
+		t.Fatalf("pool_id tie-breaker missing or in the wrong direction: %+v", orders)
+	}
+	sql, _ := searchSql(Filters{Eq(ColPoolChainId, "97")}, orders)
+	want := "ORDER BY " + ColInterestRate.expr + " DESC," + ColEndTime.expr + "," + ColPoolPoolId.expr
+	if !strings.Contains(sql, want) {
+		t.Errorf("order missing\n got %s\nwant %s", sql, want)
+	}
+}
+
+func TestDecodePoolCursorRejectsHostile(t *testing.T) {
+	orders, _ := ParsePoolSorts([]string{"interestRate:desc"})
+	other, _ := ParsePoolSorts([]string{"endTime"})
+
+	encode := func(sort string, keys []string) string {
+		b, _ := json.Marshal(poolCursor{Sort: sort, Keys: keys})
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	cursors := []string{
+		"not base64 !",
+		base64.RawURLEncoding.EncodeToString([]byte("{not json")),
+		encodePoolCursor(other, []string{"1", "2"}),
+		encode(sortSpec(orders), []string{"1"}),
+	}
+	for _, value := range hostile {
+		cursors = append(cursors,
+			encode(sortSpec(orders), []string{value, "1"}),
+			encode(value, []string{"1", "1"}),
+		)
+	}
+	for _, cursor := range cursors {
+		if keys, err := decodePoolCursor(cursor, orders); err != ErrInvalidCursor {
+			t.Errorf("%q decoded to %v, %v", cursor, keys, err)
+		}
+	}
+}
+
+func TestAfterBindsCursorKeys(t *testing.T) {
+	dryRun(t)
+	orders, _ := ParsePoolSorts([]string{"interestRate:desc", "endTime"})
+	cursor := encodePoolCursor(orders, []string{"5", "1700000000", "12"})
+	keys, err := decodePoolCursor(cursor, orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := searchSql(Filters{After(orders, []string{"0", "0", "0"})}, orders)
+	sql, vars := searchSql(Filters{After(orders, keys)}, orders)
+	if sql != want {
+		t.Errorf("cursor keys changed the sql\n got %s\nwant %s", sql, want)
+	}
+	// each key is compared once per later order column: k0; k0, k1; k0, k1, k2
+	if got := flatten(vars); strings.Join(got, ",") != "5,5,1700000000,5,1700000000,12" {
+		t.Errorf("unexpected vars %v", got)
+	}
+}
@@ -1,6 +1,9 @@
 package models
 
-import "pledge-backend/db"
+import (
+	"pledge-backend/db"
+	"pledge-backend/log"
+)
 
 func InitTable() {
 	db.Mysql.AutoMigrate(&MultiSign{})
@@ -8,4 +11,11 @@ func InitTable() {
 	db.Mysql.AutoMigrate(&TokenList{})
 	db.Mysql.AutoMigrate(&PoolData{})
 	db.Mysql.AutoMigrate(&PoolBases{})
+	db.Mysql.AutoMigrate(&Admin{})
+	db.Mysql.AutoMigrate(&RolePermission{})
+	db.Mysql.AutoMigrate(&ApiKey{})
+	db.Mysql.AutoMigrate(&AuditLog{})
+	if err := NewRolePermission().Seed(); err != nil {
+		log.Logger.Sugar().Error("seed role permissions err ", err)
+	}
 }
@@ -1,81 +1,329 @@
 package kucoin
 
 import (
-	"github.com/Kucoin/kucoin-go-sdk"
+	"context"
+	"encoding/json"
+	"errors"
+	"pledge-backend/config"
 	"pledge-backend/db"
 	"pledge-backend/log"
+	"pledge-backend/metrics"
+	"strings"
+	"time"
+
+	"github.com/Kucoin/kucoin-go-sdk"
 )
 
 // ApiKeyVersionV2 is v2 api key version
 const ApiKeyVersionV2 = "2"
 
-var PlgrPrice = "0.0027"
-var PlgrPriceChan = make(chan string, 2)
+const PlgrSymbol = "PLGR-USDT"
+
+const tickerTopic = "/market/ticker:"
+
+// Feed keeps the configured market prices in sync with the exchange. It reconnects the
+// websocket with exponential backoff and polls the REST ticker while the websocket is down.
+type Feed struct {
+	BaseUri          string
+	ApiKey           string
+	ApiSecret        string
+	ApiPassphrase    string
+	Markets          map[string]config.KucoinMarketConfig
+	MinBackoff       time.Duration
+	MaxBackoff       time.Duration
+	RestPollInterval time.Duration
+	StaleTimeout     time.Duration
+}
+
+// NewFeed create feed from the [kucoin] config, zero values fall back to defaults
+func NewFeed(conf config.KucoinConfig) *Feed {
+	f := &Feed{
+		BaseUri:          conf.BaseUri,
+		ApiKey:           conf.ApiKey,
+		ApiSecret:        conf.ApiSecret,
+		ApiPassphrase:    conf.ApiPassphrase,
+		Markets:          map[string]config.KucoinMarketConfig{},
+		MinBackoff:       time.Duration(conf.MinBackoff) * time.Second,
+		MaxBackoff:       time.Duration(conf.MaxBackoff) * time.Second,
+		RestPollInterval: time.Duration(conf.RestPollInterval) * time.Second,
+		StaleTimeout:     time.Duration(conf.StaleTimeout) * time.Second,
+	}
+	if f.MinBackoff <= 0 {
+		f.MinBackoff = time.Second
+	}
+	if f.MaxBackoff < f.MinBackoff {
+		f.MaxBackoff = 60 * time.Second
+		if f.MaxBackoff < f.MinBackoff {
+			f.MaxBackoff = f.MinBackoff
+		}
+	}
+	if f.RestPollInterval <= 0 {
+		f.RestPollInterval = 5 * time.Second
+	}
+	if f.StaleTimeout <= 0 {
+		f.StaleTimeout = 120 * time.Second
+	}
+	for _, m := range conf.Markets {
+		if m.Symbol == "" {
+			continue
+		}
+		m.Symbol = strings.ToUpper(m.Symbol)
+		f.Markets[m.Symbol] = m
+	}
+	if len(f.Markets) == 0 {
+		f.Markets[PlgrSymbol] = config.KucoinMarketConfig{
+			Symbol:  PlgrSymbol,
+			Token:   config.Config.MainNet.PlgrAddress,
+			ChainId: config.Config.MainNet.ChainId,
+		}
+	}
+	return f
+}
+
+// Symbols returns the subscribed symbols
+func (f *Feed) Symbols() []string {
+	symbols := make([]string, 0, len(f.Markets))
+	for s := range f.Markets {
+		symbols = append(symbols, s)
+	}
+	return symbols
+}
 
-func GetExchangePrice() {
+// GetExchangePrice get market prices from kucoin-exchange until ctx is cancelled
+func GetExchangePrice(ctx context.Context) {
 
 	log.Logger.Sugar().Info("GetExchangePrice ")
 
-	// get plgr price from redis
+	f := NewFeed(config.Config.Kucoin)
+	metrics.RegisterPriceAge(f.Symbols(), GetPriceAge)
+
+	// get last known prices from redis
+	for _, m := range f.Markets {
+		update := PriceUpdate{}
+		res, err := db.RedisGet("exchange_price:" + m.Symbol)
+		if err != nil || json.Unmarshal(res, &update) != nil {
+			continue
+		}
+		priceLock.Lock()
+		prices[update.Symbol] = update
+		priceLock.Unlock()
+	}
 	price, err := db.RedisGetString("plgr_price")
 	if err != nil {
 		log.Logger.Sugar().Error("get plgr price from redis err ", err)
 	} else {
+		priceLock.Lock()
 		PlgrPrice = price
+		priceLock.Unlock()
 	}
 
-	s := kucoin.NewApiService(
-		kucoin.ApiKeyOption("key"),
-		kucoin.ApiSecretOption("secret"),
-		kucoin.ApiPassPhraseOption("passphrase"),
+	f.Run(ctx)
+}
+
+// Run supervise the websocket subscription until ctx is cancelled
+func (f *Feed) Run(ctx context.Context) {
+	priceLock.Lock()
+	priceStaleTimeout = f.StaleTimeout
+	priceLock.Unlock()
+
+	go f.watchStale(ctx)
+
+	backoff := f.MinBackoff
+	for {
+		connectedAt := time.Now()
+		err := f.subscribe(ctx)
+		if ctx.Err() != nil {
+			log.Logger.Info("kucoin feed stop")
+			return
+		}
+		log.Logger.Sugar().Error("kucoin websocket closed ", err)
+
+		// a connection that stayed up long enough resets the backoff
+		if time.Since(connectedAt) > f.MaxBackoff {
+			backoff = f.MinBackoff
+		}
+
+		log.Logger.Sugar().Infof("kucoin reconnect in %s, polling rest ticker meanwhile", backoff)
+		f.pollUntil(ctx, time.Now().Add(backoff))
+
+		backoff *= 2
+		if backoff > f.MaxBackoff {
+			backoff = f.MaxBackoff
+		}
+	}
+}
+
+func (f *Feed) apiService() *kucoin.ApiService {
+	opts := []kucoin.ApiServiceOption{
+		kucoin.ApiBaseURIOption(f.BaseUri),
 		kucoin.ApiKeyVersionOption(ApiKeyVersionV2),
-	)
+	}
+	if f.ApiKey != "" {
+		opts = append(opts,
+			kucoin.ApiKeyOption(f.ApiKey),
+			kucoin.ApiSecretOption(f.ApiSecret),
+			kucoin.ApiPassPhraseOption(f.ApiPassphrase),
+		)
+	}
+	return kucoin.NewApiService(opts...)
+}
+
+// subscribe fetch a fresh public token, connect and read tickers until the connection fails or ctx is cancelled
+func (f *Feed) subscribe(ctx context.Context) error {
+	s := f.apiService()
 
 	rsp, err := s.WebSocketPublicToken()
 	if err != nil {
-		log.Logger.Error(err.Error()) // Handle error
-		return
+		return err
+	}
+	if rsp == nil {
+		return errors.New("empty websocket token response")
 	}
 
 	tk := &kucoin.WebSocketTokenModel{}
 	if err := rsp.ReadData(tk); err != nil {
-		log.Logger.Error(err.Error())
-		return
+		return err
 	}
 
 	c := s.NewWebSocketClient(tk)
 
 	mc, ec, err := c.Connect()
 	if err != nil {
-		log.Logger.Sugar().Errorf("Error: %s", err.Error())
-		return
+		return err
 	}
+	defer stopClient(c, ec)
 
-	ch := kucoin.NewSubscribeMessage("/market/ticker:PLGR-USDT", false)
-	uch := kucoin.NewUnsubscribeMessage("/market/ticker:PLGR-USDT", false)
-
-	if err := c.Subscribe(ch); err != nil {
-		log.Logger.Error(err.Error()) // Handle error
-		return
+	// one topic carries up to 100 comma separated symbols
+	symbols := f.Symbols()
+	if err := c.Subscribe(kucoin.NewSubscribeMessage(tickerTopic+strings.Join(symbols, ","), false)); err != nil {
+		return err
 	}
+	log.Logger.Sugar().Info("kucoin subscribed ", symbols)
 
 	for {
 		select {
 		case err := <-ec:
-			c.Stop() // Stop subscribing the WebSocket feed
-			log.Logger.Sugar().Errorf("Error: %s", err.Error())
-			_ = c.Unsubscribe(uch)
-			return
-		case msg := <-mc:
+			return err
+		case msg, ok := <-mc:
+			if !ok {
+				return errors.New("message channel closed")
+			}
+			symbol := strings.TrimPrefix(msg.Topic, tickerTopic)
 			t := &kucoin.TickerLevel1Model{}
 			if err := msg.ReadData(t); err != nil {
 				log.Logger.Sugar().Errorf("Failure to read: %s", err.Error())
-				return
+				continue
+			}
+			f.accept(symbol, t)
+		case <-time.After(f.StaleTimeout):
+			return errors.New("no ticker received within stale timeout")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// pollUntil poll the rest ticker of every symbol until deadline or ctx is cancelled
+func (f *Feed) pollUntil(ctx context.Context, deadline time.Time) {
+	for {
+		for symbol := range f.Markets {
+			if err := f.pollTicker(symbol); err != nil {
+				log.Logger.Sugar().Error("kucoin rest ticker err ", symbol, " ", err)
+			}
+		}
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return
+		}
+		if wait > f.RestPollInterval {
+			wait = f.RestPollInterval
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+		if !time.Now().Before(deadline) {
+			return
+		}
+	}
+}
+
+func (f *Feed) pollTicker(symbol string) error {
+	rsp, err := f.apiService().TickerLevel1(symbol)
+	if err != nil {
+		return err
+	}
+	if rsp == nil {
+		return errors.New("empty ticker response")
+	}
+	t := &kucoin.TickerLevel1Model{}
+	if err := rsp.ReadData(t); err != nil {
+		return err
+	}
+	if t.Price == "" {
+		return errors.New("ticker without price")
+	}
+	f.accept(symbol, t)
+	return nil
+}
+
+// accept publish a ticker of a configured symbol
+func (f *Feed) accept(symbol string, t *kucoin.TickerLevel1Model) {
+	m, ok := f.Markets[symbol]
+	if !ok || t.Price == "" {
+		return
+	}
+	setPrice(PriceUpdate{
+		Symbol:    symbol,
+		Token:     m.Token,
+		ChainId:   m.ChainId,
+		Price:     t.Price,
+		BestBid:   t.BestBid,
+		BestAsk:   t.BestAsk,
+		Timestamp: t.Time,
+	})
+}
+
+// watchStale log when a price stops updating and when it recovers
+func (f *Feed) watchStale(ctx context.Context) {
+	stale := map[string]bool{}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		for symbol := range f.Markets {
+			_, isStale, _ := GetPrice(symbol)
+			if isStale != stale[symbol] {
+				stale[symbol] = isStale
+				if isStale {
+					log.Logger.Sugar().Warnf("%s price stale, no update for %s", symbol, f.StaleTimeout)
+				} else {
+					log.Logger.Sugar().Info(symbol, " price recovered")
+				}
 			}
-			PlgrPriceChan <- t.Price
-			PlgrPrice = t.Price
-			//log.Logger.Sugar().Info("Price ", t.Price)
-			_ = db.RedisSetString("plgr_price", PlgrPrice, 0)
+		}
+	}
+}
+
+// stopClient stop the sdk client, its goroutines may still be pushing errors so keep draining
+func stopClient(c *kucoin.WebSocketClient, ec <-chan error) {
+	done := make(chan struct{})
+	go func() {
+		c.Stop()
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ec:
+		case <-time.After(5 * time.Second):
+			return
 		}
 	}
 }
@@ -0,0 +1,259 @@
+package kucoin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pledge-backend/config"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeExchange serves the kucoin endpoints the feed uses. Every websocket gets one ticker and is then dropped
+type fakeExchange struct {
+	*httptest.Server
+	symbol string
+
+	mu        sync.Mutex
+	connects  []time.Time
+	polls     []time.Time
+	wsPrice   string
+	restPrice string // "" fails the rest ticker
+	refuseWs  bool
+}
+
+func newFakeExchange(t *testing.T, symbol string) *fakeExchange {
+	// prices are package state, start from nothing when the test runs again
+	priceLock.Lock()
+	delete(prices, symbol)
+	delete(priceUpdatedAt, symbol)
+	priceLock.Unlock()
+
+	e := &fakeExchange{symbol: symbol, wsPrice: "1.1", restPrice: "1.2"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/bullet-public", e.token)
+	mux.HandleFunc("/api/v1/market/orderbook/level1", e.ticker)
+	mux.HandleFunc("/ws", e.socket)
+	e.Server = httptest.NewServer(mux)
+	t.Cleanup(e.Close)
+	return e
+}
+
+func (e *fakeExchange) reply(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": "200000", "data": data})
+}
+
+func (e *fakeExchange) token(w http.ResponseWriter, r *http.Request) {
+	e.reply(w, map[string]interface{}{
+		"token": "test",
+		"instanceServers": []map[string]interface{}{{
+			"endpoint":     "ws" + strings.TrimPrefix(e.URL, "http") + "/ws",
+			"protocol":     "websocket",
+			"pingInterval": 50000,
+			"pingTimeout":  10000,
+		}},
+	})
+}
+
+func (e *fakeExchange) ticker(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	e.polls = append(e.polls, time.Now())
+	price := e.restPrice
+	e.mu.Unlock()
+	if price == "" || r.URL.Query().Get("symbol") != e.symbol {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	e.reply(w, map[string]interface{}{"price": price, "bestBid": price, "bestAsk": price, "time": time.Now().UnixNano() / 1e6})
+}
+
+func (e *fakeExchange) socket(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	refuse, price := e.refuseWs, e.wsPrice
+	if !refuse {
+		e.connects = append(e.connects, time.Now())
+	}
+	e.mu.Unlock()
+	if refuse {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	_ = conn.WriteJSON(map[string]string{"id": "welcome", "type": "welcome"})
+	sub := map[string]interface{}{}
+	if err := conn.ReadJSON(&sub); err != nil {
+		return
+	}
+	_ = conn.WriteJSON(map[string]interface{}{"id": sub["id"], "type": "ack"})
+	_ = conn.WriteJSON(map[string]interface{}{
+		"type":    "message",
+		"topic":   tickerTopic + e.symbol,
+		"subject": "trade.ticker",
+		"data":    map[string]interface{}{"price": price, "time": time.Now().UnixNano() / 1e6},
+	})
+	// let the ticker through before the socket drops
+	time.Sleep(20 * time.Millisecond)
+}
+
+func (e *fakeExchange) set(f func(e *fakeExchange)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	f(e)
+}
+
+func (e *fakeExchange) snapshot() ([]time.Time, []time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]time.Time{}, e.connects...), append([]time.Time{}, e.polls...)
+}
+
+// runFeed run a feed against e until the test ends
+func runFeed(t *testing.T, e *fakeExchange, staleTimeout time.Duration) {
+	f := &Feed{
+		BaseUri:          e.URL,
+		Markets:          map[string]config.KucoinMarketConfig{e.symbol: {Symbol: e.symbol}},
+		MinBackoff:       100 * time.Millisecond,
+		MaxBackoff:       400 * time.Millisecond,
+		RestPollInterval: 50 * time.Millisecond,
+		StaleTimeout:     staleTimeout,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		f.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Error("feed did not stop")
+		}
+	})
+}
+
+func eventually(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFeedReconnectsWithBackoff(t *testing.T) {
+	e := newFakeExchange(t, "BACKOFF-USDT")
+	runFeed(t, e, time.Minute)
+
+	eventually(t, 5*time.Second, "5 connections", func() bool {
+		connects, _ := e.snapshot()
+		return len(connects) >= 5
+	})
+	connects, polls := e.snapshot()
+
+	// the wait doubles from min_backoff and stops at max_backoff
+	for i, want := range []time.Duration{100, 200, 400, 400} {
+		want *= time.Millisecond
+		gap := connects[i+1].Sub(connects[i])
+		if gap < want || gap > want+300*time.Millisecond {
+			t.Errorf("reconnect %d after %s, want about %s", i+1, gap, want)
+		}
+	}
+
+	// the rest ticker is polled while the websocket is down, and only then
+	for i := 0; i+1 < len(connects); i++ {
+		n := 0
+		for _, p := range polls {
+			if p.After(connects[i]) && p.Before(connects[i+1]) {
+				n++
+			}
+		}
+		if n == 0 {
+			t.Errorf("no rest poll between connection %d and %d", i, i+1)
+		}
+	}
+}
+
+func TestFeedFallsBackToRest(t *testing.T) {
+	e := newFakeExchange(t, "REST-USDT")
+	e.set(func(e *fakeExchange) { e.refuseWs = true })
+	runFeed(t, e, time.Minute)
+
+	eventually(t, 2*time.Second, "rest price", func() bool {
+		p, _, ok := GetPrice("REST-USDT")
+		return ok && p.Price == "1.2"
+	})
+	if _, stale, _ := GetPrice("REST-USDT"); stale {
+		t.Error("price polled from rest reported stale")
+	}
+
+	// once the websocket is back its tickers win over the polled price
+	e.set(func(e *fakeExchange) {
+		e.refuseWs = false
+		e.wsPrice = "1.3"
+		e.restPrice = ""
+	})
+	eventually(t, 2*time.Second, "websocket price", func() bool {
+		p, _, _ := GetPrice("REST-USDT")
+		return p.Price == "1.3"
+	})
+}
+
+func TestFeedMarksPriceStale(t *testing.T) {
+	staleTimeout := 300 * time.Millisecond
+	e := newFakeExchange(t, "STALE-USDT")
+	e.set(func(e *fakeExchange) { e.refuseWs = true })
+	runFeed(t, e, staleTimeout)
+
+	eventually(t, 2*time.Second, "first price", func() bool {
+		_, _, ok := GetPrice("STALE-USDT")
+		return ok
+	})
+
+	// exchange unreachable: the last price is kept but flagged once it is older than the stale timeout
+	e.set(func(e *fakeExchange) { e.restPrice = "" })
+	eventually(t, 2*time.Second, "stale flag", func() bool {
+		_, stale, _ := GetPrice("STALE-USDT")
+		return stale
+	})
+	p, _, ok := GetPrice("STALE-USDT")
+	if !ok || p.Price != "1.2" {
+		t.Errorf("stale price dropped, got %+v", p)
+	}
+	if age, ok := GetPriceAge("STALE-USDT"); !ok || age < staleTimeout {
+		t.Errorf("age %s, want at least %s", age, staleTimeout)
+	}
+
+	e.set(func(e *fakeExchange) { e.restPrice = "1.4" })
+	eventually(t, 2*time.Second, "recovery", func() bool {
+		p, stale, _ := GetPrice("STALE-USDT")
+		return !stale && p.Price == "1.4"
+	})
+}
+
+func TestNewFeedDefaults(t *testing.T) {
+	f := NewFeed(config.KucoinConfig{MinBackoff: 5, MaxBackoff: 2, Markets: []config.KucoinMarketConfig{{Symbol: "plgr-usdt"}}})
+	if f.MinBackoff != 5*time.Second || f.MaxBackoff != time.Minute {
+		t.Errorf("backoff %s - %s, want 5s - 1m0s", f.MinBackoff, f.MaxBackoff)
+	}
+	if f.RestPollInterval != 5*time.Second || f.StaleTimeout != 120*time.Second {
+		t.Errorf("poll %s stale %s, want the defaults", f.RestPollInterval, f.StaleTimeout)
+	}
+	if _, ok := f.Markets[PlgrSymbol]; !ok {
+		t.Errorf("symbol not upper cased: %v", f.Symbols())
+	}
+}
@@ -0,0 +1,104 @@
+package kucoin
+
+import (
+	"pledge-backend/db"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PriceUpdate latest ticker of one exchange symbol
+type PriceUpdate struct {
+	Symbol    string `json:"symbol"`
+	Token     string `json:"token"`
+	ChainId   string `json:"chainId"`
+	Price     string `json:"price"`
+	BestBid   string `json:"bestBid"`
+	BestAsk   string `json:"bestAsk"`
+	Timestamp int64  `json:"timestamp"` // ms
+}
+
+// PriceChan every accepted ticker is published here, updates are dropped when it is full
+var PriceChan = make(chan PriceUpdate, 64)
+
+var priceLock sync.RWMutex
+var prices = map[string]PriceUpdate{}
+var priceUpdatedAt = map[string]time.Time{}
+var priceStaleTimeout = 120 * time.Second
+
+// PlgrPrice kept for the PLGR-USDT reference, use GetPlgrPrice to read it
+var PlgrPrice = "0.0027"
+
+// GetPrice returns the latest ticker of symbol and whether it is older than the stale timeout
+func GetPrice(symbol string) (PriceUpdate, bool, bool) {
+	priceLock.RLock()
+	defer priceLock.RUnlock()
+	p, ok := prices[symbol]
+	return p, time.Since(priceUpdatedAt[symbol]) > priceStaleTimeout, ok
+}
+
+// GetPrices returns the latest ticker of every symbol, ordered by symbol
+func GetPrices() []PriceUpdate {
+	priceLock.RLock()
+	defer priceLock.RUnlock()
+	res := make([]PriceUpdate, 0, len(prices))
+	for _, p := range prices {
+		res = append(res, p)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Symbol < res[j].Symbol
+	})
+	return res
+}
+
+// GetPriceAge time since this process last received symbol, false when it never did
+func GetPriceAge(symbol string) (time.Duration, bool) {
+	priceLock.RLock()
+	defer priceLock.RUnlock()
+	updatedAt, ok := priceUpdatedAt[symbol]
+	if !ok {
+		return 0, false
+	}
+	return time.Since(updatedAt), true
+}
+
+// StaleTimeout age after which a price is reported stale
+func StaleTimeout() time.Duration {
+	priceLock.RLock()
+	defer priceLock.RUnlock()
+	return priceStaleTimeout
+}
+
+// GetPlgrPrice returns the latest PLGR price and whether it is stale
+func GetPlgrPrice() (string, bool) {
+	priceLock.RLock()
+	defer priceLock.RUnlock()
+	return PlgrPrice, time.Since(priceUpdatedAt[PlgrSymbol]) > priceStaleTimeout
+}
+
+func setPrice(update PriceUpdate) {
+	if update.Timestamp <= 0 {
+		update.Timestamp = time.Now().UnixNano() / 1e6
+	}
+
+	priceLock.Lock()
+	prices[update.Symbol] = update
+	priceUpdatedAt[update.Symbol] = time.Now()
+	if update.Symbol == PlgrSymbol {
+		PlgrPrice = update.Price
+	}
+	priceLock.Unlock()
+
+	// drop the update rather than block the feed when nobody is reading
+	select {
+	case PriceChan <- update:
+	default:
+	}
+
+	if db.RedisConn != nil {
+		_ = db.RedisSet("exchange_price:"+update.Symbol, update, 0)
+		if update.Symbol == PlgrSymbol {
+			_ = db.RedisSetString("plgr_price", update.Price, 0)
+		}
+	}
+}
@@ -6,56 +6,75 @@ import (
 	"gorm.io/gorm"
 	"pledge-backend/api/models/request"
 	"pledge-backend/db"
+	"strings"
+	"time"
 )
 
-// MultiSign multi-sign signature
+// MultiSign multi-sign signature, one row per revision, the latest effective one is current
 type MultiSign struct {
-	Id               int32  `gorm:"column:id;primaryKey"`
-	SpName           string `json:"sp_name" gorm:"column:sp_name"`
-	ChainId          int    `json:"chain_id" gorm:"column:chain_id"`
-	SpToken          string `json:"_spToken" gorm:"column:sp_token"`
-	JpName           string `json:"jp_name" gorm:"column:jp_name"`
-	JpToken          string `json:"_jpToken" gorm:"column:jp_token"`
-	SpAddress        string `json:"sp_address" gorm:"column:sp_address"`
-	JpAddress        string `json:"jp_address" gorm:"column:jp_address"`
-	SpHash           string `json:"spHash" gorm:"column:sp_hash"`
-	JpHash           string `json:"jpHash" gorm:"column:jp_hash"`
-	MultiSignAccount string `json:"multi_sign_account" gorm:"column:multi_sign_account"`
+	Id               int32      `gorm:"column:id;primaryKey"`
+	SpName           string     `json:"sp_name" gorm:"column:sp_name"`
+	ChainId          int        `json:"chain_id" gorm:"column:chain_id;uniqueIndex:idx_multi_sign_version"`
+	SpToken          string     `json:"_spToken" gorm:"column:sp_token"`
+	JpName           string     `json:"jp_name" gorm:"column:jp_name"`
+	JpToken          string     `json:"_jpToken" gorm:"column:jp_token"`
+	SpAddress        string     `json:"sp_address" gorm:"column:sp_address"`
+	JpAddress        string     `json:"jp_address" gorm:"column:jp_address"`
+	SpHash           string     `json:"spHash" gorm:"column:sp_hash"`
+	JpHash           string     `json:"jpHash" gorm:"column:jp_hash"`
+	MultiSignAccount string     `json:"multi_sign_account" gorm:"column:multi_sign_account"`
+	Version          int        `json:"version" gorm:"column:version;not null;default:1;uniqueIndex:idx_multi_sign_version"`
+	EffectiveFrom    *time.Time `json:"effective_from" gorm:"column:effective_from"` // null on rows written before revisions
+	CreatedBy        string     `json:"created_by" gorm:"column:created_by;size:100"`
 }
 
 func NewMultiSign() *MultiSign {
 	return &MultiSign{}
 }
 
-// Set Multi-Sign
-func (m *MultiSign) Set(multiSign *request.SetMultiSign) error {
+// Accounts decoded multi_sign_account
+func (m *MultiSign) Accounts() []string {
+	var accounts []string
+	_ = json.Unmarshal([]byte(m.MultiSignAccount), &accounts)
+	return accounts
+}
+
+// Set Multi-Sign, adds a revision effective from effectiveFrom, earlier revisions are kept
+func (m *MultiSign) Set(multiSign *request.SetMultiSign, effectiveFrom time.Time, createdBy string) error {
 
 	MultiSignAccountByteArr, _ := json.Marshal(multiSign.MultiSignAccount)
-	err := db.Mysql.Table("multi_sign").Where("chain_id", multiSign.ChainId).Delete(&m).Debug().Error
-	if err != nil {
-		return errors.New("record select err " + err.Error())
-	}
-	err = db.Mysql.Table("multi_sign").Where("id=?", m.Id).Create(&MultiSign{
-		ChainId:          multiSign.ChainId,
-		SpName:           multiSign.SpName,
-		SpToken:          multiSign.SpToken,
-		JpName:           multiSign.JpName,
-		JpToken:          multiSign.JpToken,
-		SpAddress:        multiSign.SpAddress,
-		JpAddress:        multiSign.JpAddress,
-		SpHash:           multiSign.SpHash,
-		JpHash:           multiSign.JpHash,
-		MultiSignAccount: string(MultiSignAccountByteArr),
-	}).Debug().Error
-	if err != nil {
-		return err
-	}
-	return nil
+	return db.Mysql.Transaction(func(tx *gorm.DB) error {
+		var version int
+		err := tx.Table("multi_sign").Where("chain_id = ?", multiSign.ChainId).
+			Select("COALESCE(MAX(version),0)").Row().Scan(&version)
+		if err != nil {
+			return errors.New("record select err " + err.Error())
+		}
+		*m = MultiSign{
+			ChainId:          multiSign.ChainId,
+			SpName:           multiSign.SpName,
+			SpToken:          multiSign.SpToken,
+			JpName:           multiSign.JpName,
+			JpToken:          multiSign.JpToken,
+			SpAddress:        multiSign.SpAddress,
+			JpAddress:        multiSign.JpAddress,
+			SpHash:           multiSign.SpHash,
+			JpHash:           multiSign.JpHash,
+			MultiSignAccount: string(MultiSignAccountByteArr),
+			Version:          version + 1,
+			EffectiveFrom:    &effectiveFrom,
+			CreatedBy:        createdBy,
+		}
+		// a concurrent Set of the same chain fails on idx_multi_sign_version instead of sharing a version
+		return tx.Table("multi_sign").Create(m).Debug().Error
+	})
 }
 
-// Get Multi-Sign
+// Get Multi-Sign, the revision of chainId in effect now, m stays empty when there is none
 func (m *MultiSign) Get(chainId int) error {
-	err := db.Mysql.Table("multi_sign").Where("chain_id", chainId).First(&m).Debug().Error
+	err := db.Mysql.Table("multi_sign").
+		Where("chain_id = ? AND (effective_from IS NULL OR effective_from <= ?)", chainId, time.Now()).
+		Order("effective_from desc").Order("version desc").First(&m).Debug().Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil
@@ -65,3 +84,24 @@ func (m *MultiSign) Get(chainId int) error {
 	}
 	return nil
 }
+
+// History every revision of chainId, newest first, including scheduled ones
+func (m *MultiSign) History(chainId int) ([]MultiSign, error) {
+	revisions := []MultiSign{}
+	err := db.Mysql.Table("multi_sign").Where("chain_id = ?", chainId).
+		Order("version desc").Find(&revisions).Debug().Error
+	return revisions, err
+}
+
+// IsOwner whether address is in the multi-sign account list of chainId, case-insensitive
+func (m *MultiSign) IsOwner(chainId int, address string) (bool, error) {
+	if err := m.Get(chainId); err != nil {
+		return false, err
+	}
+	for _, account := range m.Accounts() {
+		if strings.EqualFold(strings.TrimSpace(account), address) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
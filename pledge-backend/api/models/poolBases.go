@@ -1,10 +1,5 @@
 package models
 
-import (
-	"encoding/json"
-	"pledge-backend/db"
-)
-
 // PoolBaseInfo 资金池基础信息响应结构体
 type PoolBaseInfo struct {
 	PoolID                 int             `json:"pool_id"`                // 资金池ID
@@ -80,43 +75,12 @@ func (p *PoolBases) TableName() string {
 
 // PoolBaseInfo 获取资金池基础信息
 func (p *PoolBases) PoolBaseInfo(chainId int, res *[]PoolBaseInfoRes) error {
-	var poolBases []PoolBases
-
-	// 按链ID查询并按池ID排序
-	err := db.Mysql.Table("poolbases").Where("chain_id=?", chainId).Order("pool_id asc").Find(&poolBases).Debug().Error
+	views, err := ChainPoolViews(chainId)
 	if err != nil {
 		return err
 	}
-
-	// 遍历结果集转换数据格式
-	for _, v := range poolBases {
-		borrowTokenInfo := BorrowTokenInfo{}
-		_ = json.Unmarshal([]byte(v.BorrowTokenInfo), &borrowTokenInfo) // 解析借款代币信息
-		lendTokenInfo := LendTokenInfo{}
-		_ = json.Unmarshal([]byte(v.LendTokenInfo), &lendTokenInfo) // 解析贷款代币信息
-
-		// 组装响应数据
-		*res = append(*res, PoolBaseInfoRes{
-			Index: v.PoolID - 1, // 索引从0开始
-			PoolData: PoolBaseInfo{
-				PoolID:                 v.PoolID,
-				AutoLiquidateThreshold: v.AutoLiquidateThreshold,
-				BorrowSupply:           v.BorrowSupply,
-				BorrowToken:            v.BorrowToken,
-				BorrowTokenInfo:        borrowTokenInfo,
-				EndTime:                v.EndTime,
-				InterestRate:           v.InterestRate,
-				JpCoin:                 v.JpCoin,
-				LendSupply:             v.LendSupply,
-				LendToken:              v.LendToken,
-				LendTokenInfo:          lendTokenInfo,
-				MartgageRate:           v.MartgageRate,
-				MaxSupply:              v.MaxSupply,
-				SettleTime:             v.SettleTime,
-				SpCoin:                 v.SpCoin,
-				State:                  v.State,
-			},
-		})
+	for i := range views {
+		*res = append(*res, views[i].BaseInfo())
 	}
 	return nil
 }
@@ -1,9 +1,5 @@
 package models
 
-import (
-	"pledge-backend/db"
-)
-
 type PoolData struct {
 	Id                     int    `json:"-" gorm:"column:id;primaryKey;autoIncrement"`
 	PoolID                 int    `json:"pool_id" gorm:"column:pool_id;"`
@@ -31,19 +27,14 @@ func (p *PoolData) TableName() string {
 	return "pooldata"
 }
 
+// PoolDataInfo 获取资金池数据信息，数据与 /poolBaseInfo 同一次关联查询
 func (p *PoolData) PoolDataInfo(chainId int, res *[]PoolDataInfoRes) error {
-	var poolData []PoolData
-
-	err := db.Mysql.Table("pooldata").Where("chain_id=?", chainId).Order("pool_id asc").Find(&poolData).Debug().Error
+	views, err := ChainPoolViews(chainId)
 	if err != nil {
 		return err
 	}
-
-	for _, v := range poolData {
-		*res = append(*res, PoolDataInfoRes{
-			Index:    v.PoolID - 1,
-			PoolData: v,
-		})
+	for i := range views {
+		*res = append(*res, views[i].DataInfo())
 	}
 	return nil
 }
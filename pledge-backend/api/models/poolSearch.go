@@ -0,0 +1,250 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"pledge-backend/api/models/request"
+	"pledge-backend/db"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCursor cursor can not be decoded or belongs to another sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// MaxPoolSorts sort fields a request may combine, pool_id is always appended as tie-breaker
+const MaxPoolSorts = 3
+
+// poolbases columns
+var (
+	ColPoolChainId       = tableColumn("poolbases", "chain_id")
+	ColPoolPoolId        = tableColumn("poolbases", "pool_id")
+	ColState             = tableColumn("poolbases", "state")
+	ColLendTokenSymbol   = tableColumn("poolbases", "lend_token_symbol")
+	ColBorrowTokenSymbol = tableColumn("poolbases", "borrow_token_symbol")
+	ColInterestRate      = numericColumn("poolbases", "interest_rate")
+	ColMartgageRate      = numericColumn("poolbases", "martgage_rate")
+	ColSettleTime        = numericColumn("poolbases", "settle_time")
+	ColEndTime           = numericColumn("poolbases", "end_time")
+	ColMaxSupply         = numericColumn("poolbases", "max_supply")
+	ColLendSupply        = numericColumn("poolbases", "lend_supply")
+
+	// ColFillRatio lendSupply / maxSupply
+	ColFillRatio = Column{
+		name:    "fill_ratio",
+		expr:    fmt.Sprintf("COALESCE(%s / NULLIF(%s,0),0)", ColLendSupply.expr, ColMaxSupply.expr),
+		numeric: true,
+	}
+)
+
+// PoolSortFields sortable fields of /pool/search
+var PoolSortFields = map[string]Column{
+	"poolId":       ColPoolPoolId,
+	"interestRate": ColInterestRate,
+	"martgageRate": ColMartgageRate,
+	"settleTime":   ColSettleTime,
+	"endTime":      ColEndTime,
+	"maxSupply":    ColMaxSupply,
+	"lendSupply":   ColLendSupply,
+	"fillRatio":    ColFillRatio,
+}
+
+// FacetCount number of pools having Value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// PoolFacets counts for the filter bar, each facet ignores its own filter
+type PoolFacets struct {
+	State             []FacetCount `json:"state"`
+	LendTokenSymbol   []FacetCount `json:"lend_token_symbol"`
+	BorrowTokenSymbol []FacetCount `json:"borrow_token_symbol"`
+}
+
+// PoolPage one page of search results
+type PoolPage struct {
+	Total      int64
+	Rows       []Pool
+	NextCursor string
+}
+
+type poolCursor struct {
+	Sort string   `json:"s"`
+	Keys []string `json:"k"`
+}
+
+// PoolSearchFilters translate a validated search request into filters
+func PoolSearchFilters(req *request.Search) Filters {
+	fs := Filters{Eq(ColPoolChainId, strconv.Itoa(req.ChainID))}
+
+	if req.LendTokenSymbol != "" {
+		fs = append(fs, Eq(ColLendTokenSymbol, req.LendTokenSymbol))
+	}
+	if req.BorrowTokenSymbol != "" {
+		fs = append(fs, Eq(ColBorrowTokenSymbol, req.BorrowTokenSymbol))
+	}
+	if req.State != "" {
+		fs = append(fs, Eq(ColState, req.State))
+	}
+	if req.InterestRateMin != "" {
+		fs = append(fs, Gte(ColInterestRate, req.InterestRateMin))
+	}
+	if req.InterestRateMax != "" {
+		fs = append(fs, Lte(ColInterestRate, req.InterestRateMax))
+	}
+	if req.MartgageRateMin != "" {
+		fs = append(fs, Gte(ColMartgageRate, req.MartgageRateMin))
+	}
+	if req.MartgageRateMax != "" {
+		fs = append(fs, Lte(ColMartgageRate, req.MartgageRateMax))
+	}
+	if req.SettleTimeFrom > 0 {
+		fs = append(fs, Gte(ColSettleTime, req.SettleTimeFrom))
+	}
+	if req.SettleTimeTo > 0 {
+		fs = append(fs, Lte(ColSettleTime, req.SettleTimeTo))
+	}
+	if req.EndTimeFrom > 0 {
+		fs = append(fs, Gte(ColEndTime, req.EndTimeFrom))
+	}
+	if req.EndTimeTo > 0 {
+		fs = append(fs, Lte(ColEndTime, req.EndTimeTo))
+	}
+	if req.FillRatioMin != nil {
+		fs = append(fs, Gte(ColFillRatio, *req.FillRatioMin))
+	}
+	if req.FillRatioMax != nil {
+		fs = append(fs, Lte(ColFillRatio, *req.FillRatioMax))
+	}
+	return fs
+}
+
+// ParsePoolSorts parse ["interestRate:desc","endTime"], false on an unknown field or direction.
+// Empty input keeps the historical pool_id desc order.
+func ParsePoolSorts(specs []string) ([]Order, bool) {
+	orders := make([]Order, 0, len(specs)+1)
+	seen := map[string]bool{}
+	for _, spec := range specs {
+		parts := strings.SplitN(strings.TrimSpace(spec), ":", 2)
+		c, ok := PoolSortFields[parts[0]]
+		if !ok || seen[parts[0]] {
+			return nil, false
+		}
+		o := Order{Column: c}
+		if len(parts) == 2 {
+			switch strings.ToLower(parts[1]) {
+			case "asc":
+			case "desc":
+				o.Desc = true
+			default:
+				return nil, false
+			}
+		}
+		seen[parts[0]] = true
+		orders = append(orders, o)
+	}
+	if len(orders) > MaxPoolSorts {
+		return nil, false
+	}
+	if !seen["poolId"] {
+		desc := true
+		if len(orders) > 0 {
+			desc = orders[len(orders)-1].Desc
+		}
+		orders = append(orders, Order{Column: ColPoolPoolId, Desc: desc})
+	}
+	return orders, true
+}
+
+func sortSpec(orders []Order) string {
+	spec := make([]string, 0, len(orders))
+	for _, o := range orders {
+		if o.Desc {
+			spec = append(spec, o.Column.name+":desc")
+		} else {
+			spec = append(spec, o.Column.name+":asc")
+		}
+	}
+	return strings.Join(spec, ",")
+}
+
+func encodePoolCursor(orders []Order, keys []string) string {
+	b, _ := json.Marshal(poolCursor{Sort: sortSpec(orders), Keys: keys})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodePoolCursor(cursor string, orders []Order) ([]string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := poolCursor{}
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort != sortSpec(orders) || len(c.Keys) != len(orders) {
+		return nil, ErrInvalidCursor
+	}
+	for _, k := range c.Keys {
+		if _, err := strconv.ParseFloat(k, 64); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return c.Keys, nil
+}
+
+// Search 资金池搜索，cursor 为空时按 page 偏移分页，否则从 cursor 之后继续（keyset 分页，结果稳定）
+func (p *Pool) Search(ctx context.Context, filters Filters, orders []Order, cursor string, page int, pageSize int) (PoolPage, error) {
+	res := PoolPage{Rows: []Pool{}}
+
+	err := db.Mysql.WithContext(ctx).Table("poolbases").Scopes(filters.Scopes()...).Count(&res.Total).Error
+	if err != nil {
+		return res, err
+	}
+
+	paginate := Paginate(page, pageSize)
+	if cursor != "" {
+		keys, err := decodePoolCursor(cursor, orders)
+		if err != nil {
+			return res, err
+		}
+		filters = append(filters[:len(filters):len(filters)], After(orders, keys))
+		paginate = Paginate(1, pageSize)
+	}
+
+	views, err := FindPoolViews(filters, orders, WithContext(ctx), paginate)
+	if err != nil {
+		return res, err
+	}
+	for i := range views {
+		res.Rows = append(res.Rows, views[i].Pool())
+	}
+	if len(views) == pageSize {
+		res.NextCursor = encodePoolCursor(orders, views[len(views)-1].sortKeys(len(orders)))
+	}
+	return res, nil
+}
+
+// Facets counts per state and token under filters, each facet leaves its own filter out
+func (p *Pool) Facets(ctx context.Context, filters Filters) (PoolFacets, error) {
+	res := PoolFacets{}
+	for _, f := range []struct {
+		column Column
+		dst    *[]FacetCount
+	}{
+		{ColState, &res.State},
+		{ColLendTokenSymbol, &res.LendTokenSymbol},
+		{ColBorrowTokenSymbol, &res.BorrowTokenSymbol},
+	} {
+		*f.dst = []FacetCount{}
+		err := db.Mysql.WithContext(ctx).Table("poolbases").Select(f.column.expr + " AS value, count(*) AS count").
+			Scopes(filters.Without(f.column).Scopes()...).Where(f.column.expr + " is not null").
+			Group(f.column.expr).Order("count desc").Order(f.column.expr + " asc").
+			Scan(f.dst).Debug().Error
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"pledge-backend/db"
+	"pledge-backend/schedule/models"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// PoolView 资金池读模型：poolbases 与对应的 pooldata 一次查询取出（按 chain_id + pool_id 关联），
+// /poolBaseInfo、/poolDataInfo、/pool/search 都由它转换而来
+type PoolView struct {
+	models.PoolBase `gorm:"embedded"`
+	Data            PoolData `gorm:"embedded;embeddedPrefix:data_"`
+
+	LendInfo   LendTokenInfo   `gorm:"-"` // decoded lend_token_info
+	BorrowInfo BorrowTokenInfo `gorm:"-"` // decoded borrow_token_info
+
+	SortKey0 string `gorm:"column:sort_key_0"`
+	SortKey1 string `gorm:"column:sort_key_1"`
+	SortKey2 string `gorm:"column:sort_key_2"`
+	SortKey3 string `gorm:"column:sort_key_3"`
+}
+
+var poolDataColumns = []string{
+	"id", "pool_id", "chain_id",
+	"finish_amount_borrow", "finish_amount_lend",
+	"liquidation_amoun_borrow", "liquidation_amoun_lend",
+	"settle_amount_borrow", "settle_amount_lend",
+	"created_at", "updated_at",
+}
+
+// poolViewQuery poolbases left join pooldata, orders are selected as sort keys for keyset pagination
+func poolViewQuery(orders []Order) *gorm.DB {
+	selects := []string{"poolbases.*"}
+	for _, c := range poolDataColumns {
+		selects = append(selects, fmt.Sprintf("pooldata.%s AS data_%s", c, c))
+	}
+	for i, o := range orders {
+		selects = append(selects, fmt.Sprintf("CAST(%s AS CHAR) AS sort_key_%d", o.Column.expr, i))
+	}
+	return db.Mysql.Table("poolbases").
+		Select(strings.Join(selects, ",")).
+		Joins("LEFT JOIN pooldata ON pooldata.chain_id = poolbases.chain_id AND pooldata.pool_id = CAST(poolbases.pool_id AS CHAR)").
+		Scopes(OrderBy(orders...)...)
+}
+
+// FindPoolViews 按条件查询资金池
+func FindPoolViews(filters Filters, orders []Order, scopes ...func(*gorm.DB) *gorm.DB) ([]PoolView, error) {
+	views := []PoolView{}
+	err := poolViewQuery(orders).Scopes(filters.Scopes()...).Scopes(scopes...).Find(&views).Debug().Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range views {
+		views[i].decode()
+	}
+	return views, nil
+}
+
+// ChainPoolViews 某条链上的全部资金池，按 pool_id 升序
+func ChainPoolViews(chainId int) ([]PoolView, error) {
+	return FindPoolViews(Filters{Eq(ColPoolChainId, strconv.Itoa(chainId))}, []Order{{Column: ColPoolPoolId}})
+}
+
+// decode token info json once per row, pools without pooldata keep their own ids
+func (v *PoolView) decode() {
+	_ = json.Unmarshal([]byte(v.LendTokenInfo), &v.LendInfo)
+	_ = json.Unmarshal([]byte(v.BorrowTokenInfo), &v.BorrowInfo)
+	if v.Data.Id == 0 {
+		v.Data.PoolID = v.PoolId
+		v.Data.ChainId = v.ChainId
+	}
+}
+
+func (v *PoolView) sortKeys(n int) []string {
+	return []string{v.SortKey0, v.SortKey1, v.SortKey2, v.SortKey3}[:n]
+}
+
+// BaseInfo /poolBaseInfo 格式
+func (v *PoolView) BaseInfo() PoolBaseInfoRes {
+	return PoolBaseInfoRes{
+		Index: v.PoolId - 1, // 索引从0开始
+		PoolData: PoolBaseInfo{
+			PoolID:                 v.PoolId,
+			AutoLiquidateThreshold: v.AutoLiquidateThreshold,
+			BorrowSupply:           v.BorrowSupply,
+			BorrowToken:            v.BorrowToken,
+			BorrowTokenInfo:        v.BorrowInfo,
+			EndTime:                v.EndTime,
+			InterestRate:           v.InterestRate,
+			JpCoin:                 v.JpCoin,
+			LendSupply:             v.LendSupply,
+			LendToken:              v.LendToken,
+			LendTokenInfo:          v.LendInfo,
+			MartgageRate:           v.MartgageRate,
+			MaxSupply:              v.MaxSupply,
+			SettleTime:             v.SettleTime,
+			SpCoin:                 v.SpCoin,
+			State:                  v.State,
+		},
+	}
+}
+
+// DataInfo /poolDataInfo 格式
+func (v *PoolView) DataInfo() PoolDataInfoRes {
+	return PoolDataInfoRes{
+		Index:    v.PoolId - 1,
+		PoolData: v.Data,
+	}
+}
+
+// Pool /pool/search 格式
+func (v *PoolView) Pool() Pool {
+	return Pool{
+		PoolID:                 v.PoolId,
+		SettleTime:             v.SettleTime,
+		EndTime:                v.EndTime,
+		InterestRate:           v.InterestRate,
+		MaxSupply:              v.MaxSupply,
+		LendSupply:             v.LendSupply,
+		BorrowSupply:           v.BorrowSupply,
+		MartgageRate:           v.MartgageRate,
+		LendToken:              v.LendInfo.TokenName,
+		LendTokenSymbol:        v.LendTokenSymbol,
+		BorrowToken:            v.BorrowInfo.TokenName,
+		BorrowTokenSymbol:      v.BorrowTokenSymbol,
+		State:                  v.State,
+		SpCoin:                 v.SpCoin,
+		JpCoin:                 v.JpCoin,
+		AutoLiquidateThreshold: v.AutoLiquidateThreshold,
+		Pooldata:               v.Data,
+	}
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"pledge-backend/db"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// roles
+const (
+	RoleViewer     = "viewer"
+	RoleOperator   = "operator"
+	RoleGovernance = "governance"
+	RoleSuperAdmin = "superadmin" // always holds every permission, not editable
+
+	// RoleWallet signed in with a wallet that is not a multi-sign owner, holds no permission
+	// and can not be given to an admin account
+	RoleWallet = "wallet"
+)
+
+// permissions
+const (
+	PermPoolRead       = "pool:read"
+	PermMultiSignRead  = "multisign:read"
+	PermMultiSignWrite = "multisign:write"
+	PermSystemRead     = "system:read"
+	PermSystemWrite    = "system:write"
+	PermAdminRead      = "admin:read"
+	PermAdminWrite     = "admin:write"
+	PermRbacRead       = "rbac:read"
+	PermRbacWrite      = "rbac:write"
+	PermAuditRead      = "audit:read"
+)
+
+// Roles known roles, lowest privilege first
+var Roles = []string{RoleViewer, RoleOperator, RoleGovernance, RoleSuperAdmin}
+
+// Permissions known permissions
+var Permissions = []string{
+	PermPoolRead, PermMultiSignRead, PermMultiSignWrite, PermSystemRead, PermSystemWrite,
+	PermAdminRead, PermAdminWrite, PermRbacRead, PermRbacWrite, PermAuditRead,
+}
+
+// DefaultRolePermissions mapping seeded into an empty role_permission table
+var DefaultRolePermissions = map[string][]string{
+	RoleViewer:     {PermPoolRead, PermMultiSignRead},
+	RoleOperator:   {PermPoolRead, PermMultiSignRead, PermSystemRead},
+	RoleGovernance: {PermPoolRead, PermMultiSignRead, PermSystemRead, PermMultiSignWrite},
+}
+
+// RolePermission one granted permission of a role
+type RolePermission struct {
+	Id         int    `json:"-" gorm:"column:id;primaryKey;autoIncrement"`
+	Role       string `json:"role" gorm:"column:role;size:32;not null;uniqueIndex:idx_role_permission"`
+	Permission string `json:"permission" gorm:"column:permission;size:64;not null;uniqueIndex:idx_role_permission"`
+}
+
+func NewRolePermission() *RolePermission {
+	return &RolePermission{}
+}
+
+func (r *RolePermission) TableName() string {
+	return "role_permission"
+}
+
+// IsRole whether role is a known role
+func IsRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPermission whether permission is a known permission
+func IsPermission(permission string) bool {
+	for _, p := range Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// All current mapping, superadmin always has every permission
+func (r *RolePermission) All() (map[string][]string, error) {
+	rows := []RolePermission{}
+	err := db.Mysql.Table("role_permission").Find(&rows).Debug().Error
+	if err != nil {
+		return nil, err
+	}
+	res := map[string][]string{}
+	for _, role := range Roles {
+		res[role] = []string{}
+	}
+	for _, row := range rows {
+		if row.Role != RoleSuperAdmin {
+			res[row.Role] = append(res[row.Role], row.Permission)
+		}
+	}
+	res[RoleSuperAdmin] = append([]string{}, Permissions...)
+	for role := range res {
+		sort.Strings(res[role])
+	}
+	return res, nil
+}
+
+// Set replace the permissions of role
+func (r *RolePermission) Set(role string, permissions []string) error {
+	return db.Mysql.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("role_permission").Where("role = ?", role).Delete(&RolePermission{}).Error; err != nil {
+			return err
+		}
+		for _, p := range permissions {
+			if err := tx.Table("role_permission").Create(&RolePermission{Role: role, Permission: p}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Seed write DefaultRolePermissions when the table is empty
+func (r *RolePermission) Seed() error {
+	var total int64
+	if err := db.Mysql.Table("role_permission").Count(&total).Error; err != nil {
+		return err
+	}
+	if total > 0 {
+		return nil
+	}
+	for role, permissions := range DefaultRolePermissions {
+		if err := r.Set(role, permissions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -11,6 +11,7 @@ type SetMultiSign struct {
 	SpHash           string   `json:"spHash"`
 	JpHash           string   `json:"jpHash"`
 	MultiSignAccount []string `json:"multi_sign_account"`
+	EffectiveFrom    int64    `json:"effective_from"` // unix seconds, 0 takes effect now
 }
 
 type GetMultiSign struct {
@@ -4,3 +4,47 @@ type Login struct {
 	Name     string `form:"name" binding:"required"`
 	Password string `form:"password" binding:"required"`
 }
+
+type SiweLogin struct {
+	Message   string `json:"message" binding:"required"`   // EIP-4361 message exactly as signed
+	Signature string `json:"signature" binding:"required"` // personal_sign result, 0x hex
+}
+
+type Refresh struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type RevokeSession struct {
+	SessionId string `json:"session_id" binding:"required"`
+}
+
+type CreateAdmin struct {
+	Name     string `json:"name" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Role     string `json:"role"` // default viewer
+}
+
+type SetAdminStatus struct {
+	Name   string `json:"name" binding:"required"`
+	Status *int   `json:"status" binding:"required"` // 0 disabled, 1 enabled
+}
+
+type ChangePassword struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type ResetPassword struct {
+	Name        string `json:"name" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type SetAdminRole struct {
+	Name string `json:"name" binding:"required"`
+	Role string `json:"role" binding:"required"`
+}
+
+type SetRolePermissions struct {
+	Role        string   `json:"role" binding:"required"`
+	Permissions []string `json:"permissions"`
+}
@@ -0,0 +1,9 @@
+package request
+
+type CreateApiKey struct {
+	Name string `json:"name" binding:"required"` // integrator
+}
+
+type RevokeApiKey struct {
+	Id int `json:"id" binding:"required"`
+}
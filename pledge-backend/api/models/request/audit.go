@@ -0,0 +1,13 @@
+package request
+
+type AuditQuery struct {
+	Actor      string `form:"actor"`
+	EntityType string `form:"entity_type"` // admin, session, role, multi_sign, api_key
+	EntityId   string `form:"entity_id"`
+	Route      string `form:"route"`
+	From       int64  `form:"from"` // unix seconds, inclusive
+	To         int64  `form:"to"`   // unix seconds, inclusive
+
+	Page     int `form:"page"`
+	PageSize int `form:"pageSize"`
+}
@@ -0,0 +1,5 @@
+package request
+
+type SetLogLevel struct {
+	Level string `json:"level" binding:"required"` // debug, info, warn or error
+}
@@ -1,9 +1,26 @@
 package request
 
 type Search struct {
-	ChainID         int    `form:"chainID" json:"chainID" binding:"required"`
-	LendTokenSymbol string `form:"lend_token_symbol" json:"lend_token_symbol" binding:"omitempty"`
-	State           string `form:"state" json:"state" binding:"omitempty"`
-	Page            int    `form:"page" json:"page" `
-	PageSize        int    `form:"pageSize" json:"pageSize" `
+	ChainID           int    `form:"chainID" json:"chainID" binding:"required"`
+	LendTokenSymbol   string `form:"lend_token_symbol" json:"lend_token_symbol" binding:"omitempty"`
+	BorrowTokenSymbol string `form:"borrow_token_symbol" json:"borrow_token_symbol" binding:"omitempty"`
+	State             string `form:"state" json:"state" binding:"omitempty"`
+
+	InterestRateMin string   `form:"interestRateMin" json:"interestRateMin"` // integer, same scale as the contract
+	InterestRateMax string   `form:"interestRateMax" json:"interestRateMax"`
+	MartgageRateMin string   `form:"martgageRateMin" json:"martgageRateMin"` // collateral rate
+	MartgageRateMax string   `form:"martgageRateMax" json:"martgageRateMax"`
+	SettleTimeFrom  int64    `form:"settleTimeFrom" json:"settleTimeFrom"` // unix seconds
+	SettleTimeTo    int64    `form:"settleTimeTo" json:"settleTimeTo"`
+	EndTimeFrom     int64    `form:"endTimeFrom" json:"endTimeFrom"`
+	EndTimeTo       int64    `form:"endTimeTo" json:"endTimeTo"`
+	FillRatioMin    *float64 `form:"fillRatioMin" json:"fillRatioMin"` // lendSupply / maxSupply, 0-1
+	FillRatioMax    *float64 `form:"fillRatioMax" json:"fillRatioMax"`
+
+	Sort   []string `form:"sort" json:"sort"`     // e.g. ["interestRate:desc","endTime:asc"]
+	Cursor string   `form:"cursor" json:"cursor"` // nextCursor of the previous page, takes precedence over page
+	Facets bool     `form:"facets" json:"facets"` // also return counts per state and token
+
+	Page     int `form:"page" json:"page" `
+	PageSize int `form:"pageSize" json:"pageSize" `
 }
@@ -1,5 +1,25 @@
 package response
 
 type Login struct {
-	TokenId string `json:"token_id"`
+	TokenId          string `json:"token_id"` // access token, send as authCode header
+	RefreshToken     string `json:"refresh_token"`
+	SessionId        string `json:"session_id"`
+	ExpiresIn        int    `json:"expires_in"`         // access token lifetime, s
+	RefreshExpiresIn int    `json:"refresh_expires_in"` // refresh token lifetime, s
+}
+
+type Session struct {
+	SessionId   string `json:"session_id"`
+	Ip          string `json:"ip"`
+	UserAgent   string `json:"user_agent"`
+	CreatedAt   int64  `json:"created_at"`
+	RefreshedAt int64  `json:"refreshed_at"`
+	Current     bool   `json:"current"`
+}
+
+type SiweNonce struct {
+	Nonce     string `json:"nonce"`
+	Domain    string `json:"domain"`     // domain the message must be signed for
+	ChainIds  []int  `json:"chain_ids"`  // chains the message may be signed for
+	ExpiresIn int    `json:"expires_in"` // s
 }
@@ -0,0 +1,8 @@
+package response
+
+type ApiKey struct {
+	Id     int    `json:"id"`
+	Name   string `json:"name"`
+	Prefix string `json:"prefix"`
+	Key    string `json:"key"` // shown only once, send as X-API-Key header
+}
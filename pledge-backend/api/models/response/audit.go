@@ -0,0 +1,8 @@
+package response
+
+import "pledge-backend/api/models"
+
+type AuditLogs struct {
+	Count int64             `json:"count"`
+	Rows  []models.AuditLog `json:"rows"`
+}
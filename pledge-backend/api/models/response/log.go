@@ -0,0 +1,6 @@
+package response
+
+type LogLevel struct {
+	Level    string            `json:"level"`    // default level, changeable
+	Packages map[string]string `json:"packages"` // per package overrides from [log.packages]
+}
@@ -11,4 +11,19 @@ type MultiSign struct {
 	SpHash           string   `json:"spHash"`
 	JpHash           string   `json:"jpHash"`
 	MultiSignAccount []string `json:"multi_sign_account"`
+	Version          int      `json:"version"`
+	EffectiveFrom    int64    `json:"effective_from"` // unix seconds, 0 for revisions older than versioning
+	CreatedBy        string   `json:"created_by"`
+
+	Verification *MultiSignVerification `json:"verification,omitempty"`
+}
+
+// MultiSignVerification multi_sign_account compared with the signer set of the multiSignature contract
+type MultiSignVerification struct {
+	Status        string   `json:"status"` // match, mismatch or unavailable
+	OnchainOwners []string `json:"onchain_owners"`
+	Threshold     int64    `json:"threshold"`
+	Missing       []string `json:"missing"`    // on chain, not in multi_sign_account
+	Unexpected    []string `json:"unexpected"` // in multi_sign_account, not on chain
+	Error         string   `json:"error,omitempty"`
 }
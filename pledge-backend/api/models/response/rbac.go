@@ -0,0 +1,6 @@
+package response
+
+type Roles struct {
+	Roles       map[string][]string `json:"roles"`
+	Permissions []string            `json:"permissions"`
+}
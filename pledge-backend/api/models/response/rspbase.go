@@ -24,6 +24,7 @@ func (g *Gin) ResponsePages(c *gin.Context, code int, totalCount int, data inter
 	if hasLang {
 		lang = langInf.(int)
 	}
+	c.Set("response_code", code)
 	rsp := Page{
 		Code:  code,
 		Msg:   statecode.GetMsg(code, lang),
@@ -41,6 +42,7 @@ func (g *Gin) Response(c *gin.Context, code int, data interface{}, httpStatus ..
 	if hasLang {
 		lang = langInf.(int)
 	}
+	c.Set("response_code", code)
 	rsp := Response{
 		Code: code,
 		Msg:  statecode.GetMsg(code, lang),
@@ -3,6 +3,8 @@ package response
 import "pledge-backend/api/models"
 
 type Search struct {
-	Count int64         `json:"count"`
-	Rows  []models.Pool `json:"rows"`
+	Count      int64              `json:"count"`
+	Rows       []models.Pool      `json:"rows"`
+	NextCursor string             `json:"nextCursor,omitempty"` // empty on the last page
+	Facets     *models.PoolFacets `json:"facets,omitempty"`
 }
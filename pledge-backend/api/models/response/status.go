@@ -0,0 +1,74 @@
+package response
+
+// dependency and overall status values
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusOk       = "ok"
+	StatusDegraded = "degraded"
+)
+
+// Dependency result of one check, latency of the probe in ms
+type Dependency struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Ready body of /readyz
+type Ready struct {
+	Status       string       `json:"status"`
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// ChainStatus rpc node of a chain and how far the scheduler has synced it
+type ChainStatus struct {
+	ChainId     string     `json:"chainId"`
+	Rpc         Dependency `json:"rpc"`
+	HeadBlock   uint64     `json:"headBlock"`   // latest block on the rpc node, 0 when it is down
+	SyncedBlock uint64     `json:"syncedBlock"` // block the last completed pool sync started from
+	SyncedAt    int64      `json:"syncedAt"`
+	LagBlocks   int64      `json:"lagBlocks"`
+}
+
+// JobStatus last run of a scheduler job, unix seconds
+type JobStatus struct {
+	Name        string `json:"name"`
+	Schedule    string `json:"schedule"`
+	Running     bool   `json:"running"`
+	LastRun     int64  `json:"lastRun"`
+	NextRun     int64  `json:"nextRun"`
+	LastSuccess int64  `json:"lastSuccess"`
+	DurationMs  int64  `json:"durationMs"`
+	Attempts    int    `json:"attempts"`
+	Aborted     bool   `json:"aborted"`
+	Error       string `json:"error,omitempty"`
+}
+
+// PriceFeedStatus how old the latest kucoin ticker of a symbol is, -1 when none was received since start
+type PriceFeedStatus struct {
+	Symbol     string `json:"symbol"`
+	Price      string `json:"price"`
+	AgeSeconds int64  `json:"ageSeconds"`
+	Stale      bool   `json:"stale"`
+}
+
+// Status body of /status
+type Status struct {
+	Status        string            `json:"status"`
+	Version       string            `json:"version"`
+	StartedAt     int64             `json:"startedAt"`
+	ConfigVersion int64             `json:"configVersion"`
+	Dependencies  []Dependency      `json:"dependencies"`
+	Chains        []ChainStatus     `json:"chains"`
+	Jobs          []JobStatus       `json:"jobs"`
+	PriceFeed     []PriceFeedStatus `json:"priceFeed"`
+}
+
+// Degrade mark an ok status degraded, down stays down
+func (s *Status) Degrade() {
+	if s.Status == StatusOk {
+		s.Status = StatusDegraded
+	}
+}
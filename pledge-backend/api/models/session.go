@@ -0,0 +1,192 @@
+package models
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"pledge-backend/config"
+	"pledge-backend/db"
+	"pledge-backend/log"
+	"pledge-backend/utils"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrSessionNotFound session expired or revoked
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrRefreshToken malformed, rotated away or used concurrently
+	ErrRefreshToken = errors.New("invalid refresh token")
+)
+
+// Session 登录会话，存在 Redis：session:<id> 为会话内容，sessions:<username> 为该账号全部会话 id。
+// refresh token 为 "<id>.<secret>"，只保存 secret 的 sha256，每次刷新都换新的 secret
+type Session struct {
+	SessionId   string `json:"session_id"`
+	Username    string `json:"username"`
+	Address     string `json:"address,omitempty"`  // wallet sessions (siwe) only
+	ChainId     int    `json:"chain_id,omitempty"` // wallet sessions (siwe) only
+	RefreshHash string `json:"refresh_hash"`
+	PrevHash    string `json:"prev_hash,omitempty"` // secret rotated away last, presenting it again means the token leaked
+	Ip          string `json:"ip"`
+	UserAgent   string `json:"user_agent"`
+	CreatedAt   int64  `json:"created_at"`
+	RefreshedAt int64  `json:"refreshed_at"`
+
+	raw string // value read from redis, compared when rotating
+}
+
+func NewSession() *Session {
+	return &Session{}
+}
+
+func sessionKey(id string) string {
+	return "session:" + id
+}
+
+func sessionIndexKey(username string) string {
+	return "sessions:" + username
+}
+
+func sessionTTL() int {
+	return config.Config.Jwt.RefreshExpireTime
+}
+
+func newRefreshSecret() (secret string, hash string, err error) {
+	secret, err = utils.RandomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+	return secret, utils.Sha256Hex(secret), nil
+}
+
+// Create start a session for username, returns its refresh token. Address and ChainId already set on s are kept.
+func (s *Session) Create(username string, ip string, userAgent string) (string, error) {
+	id, err := utils.RandomHex(16)
+	if err != nil {
+		return "", err
+	}
+	secret, hash, err := newRefreshSecret()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().Unix()
+	*s = Session{
+		SessionId:   id,
+		Username:    username,
+		Address:     s.Address,
+		ChainId:     s.ChainId,
+		RefreshHash: hash,
+		Ip:          ip,
+		UserAgent:   userAgent,
+		CreatedAt:   now,
+		RefreshedAt: now,
+	}
+	if err = db.RedisSet(sessionKey(id), s, sessionTTL()); err != nil {
+		return "", err
+	}
+	if db.RedisSAdd(sessionIndexKey(username), id) < 0 {
+		return "", errors.New("session index write failed")
+	}
+	if err = db.RedisExpire(sessionIndexKey(username), sessionTTL()); err != nil {
+		return "", err
+	}
+	return id + "." + secret, nil
+}
+
+// Get load session id into s, ErrSessionNotFound when it expired or was revoked
+func (s *Session) Get(id string) error {
+	raw, err := db.RedisGet(sessionKey(id))
+	if err != nil || len(raw) == 0 {
+		return ErrSessionNotFound
+	}
+	if err = json.Unmarshal(raw, s); err != nil {
+		return err
+	}
+	s.raw = string(raw)
+	return nil
+}
+
+// Rotate exchange refreshToken for a new one. Presenting the refresh token that was just
+// rotated away revokes the whole session, one of the two holders is not the owner.
+// Any other wrong secret is only refused, the session id alone is in every access token.
+func (s *Session) Rotate(refreshToken string) (string, error) {
+	parts := strings.SplitN(refreshToken, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", ErrRefreshToken
+	}
+	if err := s.Get(parts[0]); err != nil {
+		return "", err
+	}
+	presented := []byte(utils.Sha256Hex(parts[1]))
+	if subtle.ConstantTimeCompare(presented, []byte(s.RefreshHash)) != 1 {
+		if s.PrevHash != "" && subtle.ConstantTimeCompare(presented, []byte(s.PrevHash)) == 1 {
+			log.Logger.Sugar().Warn("refresh token reused, revoking session ", s.SessionId, " of ", s.Username)
+			_ = s.Revoke()
+		}
+		return "", ErrRefreshToken
+	}
+
+	secret, hash, err := newRefreshSecret()
+	if err != nil {
+		return "", err
+	}
+	s.PrevHash, s.RefreshHash = s.RefreshHash, hash
+	s.RefreshedAt = time.Now().Unix()
+	value, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	ok, err := db.RedisCompareAndSet(sessionKey(s.SessionId), s.raw, string(value), sessionTTL())
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		// another refresh with the same token won the race
+		return "", ErrRefreshToken
+	}
+	s.raw = string(value)
+	_ = db.RedisExpire(sessionIndexKey(s.Username), sessionTTL())
+	return s.SessionId + "." + secret, nil
+}
+
+// Revoke end session s
+func (s *Session) Revoke() error {
+	if _, err := db.RedisDelete(sessionKey(s.SessionId)); err != nil {
+		return err
+	}
+	return db.RedisSRem(sessionIndexKey(s.Username), s.SessionId)
+}
+
+// List live sessions of username, ids of expired sessions are dropped from the index
+func (s *Session) List(username string) ([]Session, error) {
+	ids, err := db.RedisSmembers(sessionIndexKey(username))
+	if err != nil {
+		return nil, err
+	}
+	sessions := []Session{}
+	for _, id := range ids {
+		session := NewSession()
+		if err := session.Get(id); err != nil || session.Username != username {
+			_ = db.RedisSRem(sessionIndexKey(username), id)
+			continue
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, nil
+}
+
+// RevokeAll end every session of username
+func (s *Session) RevokeAll(username string) error {
+	ids, err := db.RedisSmembers(sessionIndexKey(username))
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err := db.RedisDelete(sessionKey(id)); err != nil {
+			return err
+		}
+	}
+	_, err = db.RedisDelete(sessionIndexKey(username))
+	return err
+}
@@ -33,7 +33,7 @@ func NewTokenInfo() *TokenInfo {
 // 返回值修正为：([]TokenInfo, error) - 错误作为最后一个返回值
 func (m *TokenInfo) GetTokenInfo(req *request.TokenList) ([]TokenInfo, error) {
 	var tokenInfo = make([]TokenInfo, 0)
-	err := db.Mysql.Table("token_info").Where("chain_id", req.ChainId).Find(&tokenInfo).Debug().Error
+	err := db.Mysql.Table("token_info").Scopes(Eq(ColChainId, req.ChainId).Scope()).Find(&tokenInfo).Debug().Error
 	if err != nil {
 		return nil, errors.New("record select err " + err.Error())
 	}
@@ -44,7 +44,7 @@ func (m *TokenInfo) GetTokenInfo(req *request.TokenList) ([]TokenInfo, error) {
 // 返回值修正为：([]TokenList, error) - 错误作为最后一个返回值
 func (m *TokenInfo) GetTokenList(req *request.TokenList) ([]TokenList, error) {
 	var tokenList = make([]TokenList, 0)
-	err := db.Mysql.Table("token_info").Where("chain_id", req.ChainId).Find(&tokenList).Debug().Error
+	err := db.Mysql.Table("token_info").Scopes(Eq(ColChainId, req.ChainId).Scope()).Find(&tokenList).Debug().Error
 	if err != nil {
 		return nil, errors.New("record select err " + err.Error())
 	}
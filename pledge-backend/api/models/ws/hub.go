@@ -0,0 +1,374 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/config"
+	"pledge-backend/log"
+	"pledge-backend/metrics"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Publication data for every connection subscribed to Topic
+type Publication struct {
+	Topic string
+	Data  interface{}
+}
+
+// record a published frame kept for Last-Event-ID resume
+type record struct {
+	Seq   uint64
+	Topic string
+	Data  []byte
+}
+
+// historySize number of frames kept for stream resume
+const historySize = 1024
+
+type attachReq struct {
+	stream *Stream
+	lastId EventId
+	reply  chan int
+}
+
+// ServerManager the hub, all connection bookkeeping happens in Run
+type ServerManager struct {
+	Broadcast  chan Publication
+	Register   chan *Server
+	Unregister chan *Server
+
+	Detach chan *Stream
+
+	attach     chan attachReq
+	metricsReq chan chan Metrics
+	drain      chan chan []func()
+	closing    bool // shutting down, new connections are refused
+	servers    map[string]*Server
+	streams    map[*Stream]struct{}
+	ipCount    map[string]int
+
+	epoch   int64 // start of this hub, event ids of another process never match it
+	seq     uint64
+	history []record // ring buffer, history[seq%historySize]
+
+	MaxConnections int
+	MaxConnPerIp   int
+
+	published uint64
+	evicted   uint64
+	rejected  uint64
+}
+
+// Metrics snapshot of the hub
+type Metrics struct {
+	Connections    int             `json:"connections"`
+	Streams        int             `json:"streams"`
+	MaxConnections int             `json:"maxConnections"`
+	Published      uint64          `json:"published"`
+	Evicted        uint64          `json:"evicted"`
+	Rejected       uint64          `json:"rejected"`
+	Clients        []ClientMetrics `json:"clients"`
+}
+
+// ClientMetrics one connected client
+type ClientMetrics struct {
+	Id          string   `json:"id"`
+	Ip          string   `json:"ip"`
+	ConnectedAt int64    `json:"connectedAt"`
+	LastTime    int64    `json:"lastTime"`
+	Queued      int      `json:"queued"`
+	Topics      []string `json:"topics"`
+}
+
+var Manager = NewServerManager()
+
+func NewServerManager() *ServerManager {
+	m := &ServerManager{
+		Broadcast:      make(chan Publication, 256),
+		Register:       make(chan *Server),
+		Unregister:     make(chan *Server),
+		Detach:         make(chan *Stream),
+		attach:         make(chan attachReq),
+		metricsReq:     make(chan chan Metrics),
+		drain:          make(chan chan []func()),
+		servers:        map[string]*Server{},
+		streams:        map[*Stream]struct{}{},
+		epoch:          time.Now().UnixNano(),
+		history:        make([]record, historySize),
+		ipCount:        map[string]int{},
+		MaxConnections: config.Config.Env.WssMaxConnections,
+		MaxConnPerIp:   config.Config.Env.WssMaxConnPerIp,
+	}
+	if m.MaxConnections <= 0 {
+		m.MaxConnections = 10000
+	}
+	if m.MaxConnPerIp <= 0 {
+		m.MaxConnPerIp = 50
+	}
+	return m
+}
+
+// Run the hub event loop, the only place servers is touched
+func (m *ServerManager) Run() {
+	for {
+		select {
+		case s := <-m.Register:
+			if m.closing {
+				go s.close(websocket.CloseGoingAway, "server shutting down")
+			} else if m.full(s.Ip) {
+				m.rejected++
+				metrics.WsDropped.WithLabelValues("rejected").Inc()
+				log.Logger.Sugar().Warn(s.Id, " websocket rejected, connection limit reached")
+				go s.close(websocket.CloseTryAgainLater, "too many connections")
+			} else {
+				m.servers[s.Id] = s
+				m.ipCount[s.Ip]++
+			}
+		case s := <-m.Unregister:
+			m.remove(s)
+		case req := <-m.attach:
+			req.reply <- m.attachStream(req.stream, req.lastId)
+		case st := <-m.Detach:
+			m.detachStream(st)
+		case p := <-m.Broadcast:
+			m.publish(p)
+		case reply := <-m.metricsReq:
+			reply <- m.snapshot()
+		case reply := <-m.drain:
+			reply <- m.drainAll()
+		}
+		m.observe()
+	}
+}
+
+// observe export the connection counts, called after every hub event
+func (m *ServerManager) observe() {
+	metrics.WsConnections.WithLabelValues("websocket").Set(float64(len(m.servers)))
+	metrics.WsConnections.WithLabelValues("sse").Set(float64(len(m.streams)))
+}
+
+// Publish queue data for the subscribers of topic
+func (m *ServerManager) Publish(topic string, data interface{}) {
+	m.Broadcast <- Publication{Topic: topic, Data: data}
+}
+
+// Attach register an sse stream and replay the frames published after lastId,
+// returns statecode.CommonSuccess or the reason the stream was refused
+func (m *ServerManager) Attach(st *Stream, lastId EventId) int {
+	reply := make(chan int, 1)
+	m.attach <- attachReq{stream: st, lastId: lastId, reply: reply}
+	return <-reply
+}
+
+// Shutdown refuse new connections, end every sse stream and close every websocket with a going away frame,
+// returns once the close frames are written or ctx is done
+func (m *ServerManager) Shutdown(ctx context.Context) error {
+	reply := make(chan []func(), 1)
+	select {
+	case m.drain <- reply:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	closers := <-reply
+
+	// close frames are written concurrently, a slow client must not hold up the others
+	var wg sync.WaitGroup
+	for _, closer := range closers {
+		wg.Add(1)
+		go func(closer func()) {
+			defer wg.Done()
+			closer()
+		}(closer)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// drainAll forget every connection, streams end right away, websockets are returned to be closed outside the loop
+func (m *ServerManager) drainAll() []func() {
+	m.closing = true
+	for st := range m.streams {
+		m.detachStream(st)
+	}
+	closers := make([]func(), 0, len(m.servers))
+	for id, s := range m.servers {
+		delete(m.servers, id)
+		m.ipCount[s.Ip]--
+		if m.ipCount[s.Ip] <= 0 {
+			delete(m.ipCount, s.Ip)
+		}
+		s := s
+		closers = append(closers, func() { s.close(websocket.CloseGoingAway, "server shutting down") })
+	}
+	log.Logger.Sugar().Info("websocket draining ", len(closers), " connections")
+	return closers
+}
+
+// Snapshot current connections, safe to call from any goroutine
+func (m *ServerManager) Snapshot() Metrics {
+	reply := make(chan Metrics, 1)
+	m.metricsReq <- reply
+	return <-reply
+}
+
+func (m *ServerManager) full(ip string) bool {
+	return len(m.servers)+len(m.streams) >= m.MaxConnections || m.ipCount[ip] >= m.MaxConnPerIp
+}
+
+func (m *ServerManager) attachStream(st *Stream, lastId EventId) int {
+	// shutting down, the client reconnects to another instance
+	if m.closing {
+		return statecode.WsConnLimitErr
+	}
+	if m.full(st.Ip) {
+		m.rejected++
+		metrics.WsDropped.WithLabelValues("rejected").Inc()
+		log.Logger.Sugar().Warn(st.Id, " stream rejected, connection limit reached")
+		return statecode.WsConnLimitErr
+	}
+	// replay inside the loop so nothing published in between is lost or duplicated. An id of another
+	// process, one older than the history or a replay the send queue can not hold starts the stream
+	// afresh, Serve sends the snapshot then
+	st.epoch = m.epoch
+	if lastId.Epoch == m.epoch && lastId.Seq > 0 && lastId.Seq <= m.seq && m.seq-lastId.Seq <= historySize {
+		st.resumed = true
+		for seq := lastId.Seq + 1; seq <= m.seq; seq++ {
+			r := m.history[seq%historySize]
+			if st.IsSubscribed(r.Topic) && !st.enqueue(r) {
+				st.resumed = false
+				for len(st.Send) > 0 {
+					<-st.Send
+				}
+				break
+			}
+		}
+	}
+	m.streams[st] = struct{}{}
+	m.ipCount[st.Ip]++
+	return statecode.CommonSuccess
+}
+
+func (m *ServerManager) detachStream(st *Stream) {
+	if _, ok := m.streams[st]; ok {
+		delete(m.streams, st)
+		m.ipCount[st.Ip]--
+		if m.ipCount[st.Ip] <= 0 {
+			delete(m.ipCount, st.Ip)
+		}
+	}
+	st.close()
+}
+
+// remove forget s, the close frame is written outside the loop so a client that does not read can not stall the hub
+func (m *ServerManager) remove(s *Server) {
+	if cur, ok := m.servers[s.Id]; ok && cur == s {
+		delete(m.servers, s.Id)
+		m.ipCount[s.Ip]--
+		if m.ipCount[s.Ip] <= 0 {
+			delete(m.ipCount, s.Ip)
+		}
+	}
+	go s.close(websocket.CloseNormalClosure, "")
+}
+
+// publish marshal once and hand the frame to each subscriber's write pump,
+// a client whose queue is full is evicted instead of slowing down everyone else
+func (m *ServerManager) publish(p Publication) {
+	data, err := marshalEvent(p.Topic, p.Data)
+	if err != nil {
+		log.Logger.Sugar().Error("publish marshal err ", err)
+		return
+	}
+	m.published++
+	metrics.WsPublished.Inc()
+	m.seq++
+	r := record{Seq: m.seq, Topic: p.Topic, Data: data}
+	m.history[m.seq%historySize] = r
+
+	for st := range m.streams {
+		if !st.IsSubscribed(p.Topic) {
+			continue
+		}
+		if !st.enqueue(r) {
+			m.evicted++
+			metrics.WsDropped.WithLabelValues("evicted").Inc()
+			log.Logger.Sugar().Warn(st.Id, " stream evicted, slow consumer")
+			m.detachStream(st)
+		}
+	}
+	var legacy []byte
+	if p.Topic == LegacyPriceTopic {
+		if legacy, err = marshalLegacyPrice(p.Data); err != nil {
+			log.Logger.Sugar().Error("publish legacy marshal err ", err)
+		}
+	}
+	for _, s := range m.servers {
+		frame := data
+		if s.legacy() {
+			// clients without the protocol only get the plgr price, in the frame they know
+			if legacy == nil {
+				continue
+			}
+			frame = legacy
+		} else if !s.IsSubscribed(p.Topic) {
+			continue
+		}
+		if !s.enqueue(frame) {
+			m.evicted++
+			metrics.WsDropped.WithLabelValues("evicted").Inc()
+			log.Logger.Sugar().Warn(s.Id, " websocket evicted, slow consumer")
+			m.remove(s)
+		}
+	}
+}
+
+// marshalEvent the event envelope shared by websocket and sse subscribers
+func marshalEvent(topic string, data interface{}) ([]byte, error) {
+	return json.Marshal(Envelope{
+		V:     ProtocolVersion,
+		Type:  TypeEvent,
+		Topic: topic,
+		Data:  data,
+		Ts:    time.Now().UnixNano() / 1e6,
+	})
+}
+
+func (m *ServerManager) snapshot() Metrics {
+	res := Metrics{
+		Connections:    len(m.servers),
+		Streams:        len(m.streams),
+		MaxConnections: m.MaxConnections,
+		Published:      m.published,
+		Evicted:        m.evicted,
+		Rejected:       m.rejected,
+		Clients:        make([]ClientMetrics, 0, len(m.servers)),
+	}
+	for _, s := range m.servers {
+		res.Clients = append(res.Clients, ClientMetrics{
+			Id:          s.Id,
+			Ip:          s.Ip,
+			ConnectedAt: s.ConnectedAt.Unix(),
+			LastTime:    s.lastTime(),
+			Queued:      len(s.Send),
+			Topics:      s.Topics(),
+		})
+	}
+	sort.Slice(res.Clients, func(i, j int) bool {
+		return res.Clients[i].ConnectedAt < res.Clients[j].ConnectedAt
+	})
+	return res
+}
@@ -0,0 +1,127 @@
+package ws
+
+import (
+	"net/http/httptest"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models/kucoin"
+	"strconv"
+	"testing"
+)
+
+const testTopic = TopicPrice + ":PLGR-USDT"
+
+func newTestStream(t *testing.T) *Stream {
+	t.Helper()
+	st, code := NewStream("sse_test", "10.0.0.1", []string{testTopic})
+	if code != statecode.CommonSuccess {
+		t.Fatalf("new stream %d", code)
+	}
+	return st
+}
+
+// hubWith a hub that published n frames, driven directly instead of through Run
+func hubWith(n int) *ServerManager {
+	m := NewServerManager()
+	for i := 0; i < n; i++ {
+		m.publish(Publication{Topic: testTopic, Data: kucoin.PriceUpdate{Symbol: "PLGR-USDT", Price: strconv.Itoa(i)}})
+	}
+	return m
+}
+
+// attach st to m after lastId, returns the seqs replayed to it
+func attach(t *testing.T, m *ServerManager, st *Stream, lastId EventId) []uint64 {
+	t.Helper()
+	if code := m.attachStream(st, lastId); code != statecode.CommonSuccess {
+		t.Fatalf("attach refused with %d", code)
+	}
+	var seqs []uint64
+	for len(st.Send) > 0 {
+		seqs = append(seqs, (<-st.Send).Seq)
+	}
+	return seqs
+}
+
+func TestAttachReplaysAfterLastEventId(t *testing.T) {
+	m := hubWith(5)
+	st := newTestStream(t)
+	seqs := attach(t, m, st, EventId{Epoch: m.epoch, Seq: 3})
+	if len(seqs) != 2 || seqs[0] != 4 || seqs[1] != 5 {
+		t.Errorf("replayed %v, want [4 5]", seqs)
+	}
+	if !st.resumed || st.epoch != m.epoch {
+		t.Errorf("resumed %v epoch %d, want true %d", st.resumed, st.epoch, m.epoch)
+	}
+
+	// up to date, nothing to replay and no snapshot either
+	st = newTestStream(t)
+	if seqs := attach(t, m, st, EventId{Epoch: m.epoch, Seq: 5}); len(seqs) != 0 || !st.resumed {
+		t.Errorf("replayed %v resumed %v, want nothing and resumed", seqs, st.resumed)
+	}
+}
+
+func TestAttachStartsAfreshAfterRestart(t *testing.T) {
+	before := hubWith(50)
+	after := hubWith(3)
+	after.epoch = before.epoch + 1 // a restart within the clock resolution
+
+	for name, lastId := range map[string]EventId{
+		"id of the previous process": {Epoch: before.epoch, Seq: 40},
+		"seq ahead of this process":  {Epoch: after.epoch, Seq: 40},
+		"id without epoch":           {Seq: 2},
+		"no id":                      {},
+	} {
+		st := newTestStream(t)
+		if seqs := attach(t, after, st, lastId); len(seqs) != 0 || st.resumed {
+			t.Errorf("%s: replayed %v resumed %v, want a fresh start", name, seqs, st.resumed)
+		}
+		if st.epoch != after.epoch {
+			t.Errorf("%s: stream numbered with epoch %d, want %d", name, st.epoch, after.epoch)
+		}
+	}
+}
+
+func TestAttachStartsAfreshWhenHistoryIsGone(t *testing.T) {
+	m := hubWith(historySize + 10)
+	st := newTestStream(t)
+	if seqs := attach(t, m, st, EventId{Epoch: m.epoch, Seq: 5}); len(seqs) != 0 || st.resumed {
+		t.Errorf("replayed %d frames resumed %v, want a fresh start", len(seqs), st.resumed)
+	}
+
+	// more than the send queue holds, a partial replay would lose frames
+	st = newTestStream(t)
+	if seqs := attach(t, m, st, EventId{Epoch: m.epoch, Seq: m.seq - uint64(cap(st.Send)) - 1}); len(seqs) != 0 || st.resumed {
+		t.Errorf("replayed %d frames resumed %v, want a fresh start", len(seqs), st.resumed)
+	}
+
+	// the oldest position still in the history replays all of it
+	st = newTestStream(t)
+	st.Send = make(chan record, historySize)
+	seqs := attach(t, m, st, EventId{Epoch: m.epoch, Seq: m.seq - historySize})
+	if !st.resumed || len(seqs) != historySize || seqs[0] != m.seq-historySize+1 {
+		t.Errorf("replayed %d frames resumed %v, want %d", len(seqs), st.resumed, historySize)
+	}
+}
+
+func TestParseLastEventId(t *testing.T) {
+	for v, want := range map[string]EventId{
+		"":                   {},
+		"42":                 {},
+		"abc-1":              {},
+		"1700000000000-x":    {},
+		"1700000000000-42":   {Epoch: 1700000000000, Seq: 42},
+		" 1700000000000-42 ": {Epoch: 1700000000000, Seq: 42},
+	} {
+		r := httptest.NewRequest("GET", "/api/v21/price/stream", nil)
+		r.Header.Set("Last-Event-ID", v)
+		if got := ParseLastEventId(r); got != want {
+			t.Errorf("%q parsed to %+v, want %+v", v, got, want)
+		}
+	}
+	r := httptest.NewRequest("GET", "/api/v21/price/stream?lastEventId=7-8", nil)
+	if got := ParseLastEventId(r); got != (EventId{Epoch: 7, Seq: 8}) {
+		t.Errorf("query parsed to %+v", got)
+	}
+	if id := (EventId{Epoch: 7, Seq: 8}); id.String() != "7-8" {
+		t.Errorf("event id %q, want 7-8", id.String())
+	}
+}
@@ -0,0 +1,37 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"pledge-backend/db"
+	"pledge-backend/log"
+	"pledge-backend/schedule/models"
+	"time"
+)
+
+// StartPoolListener forward pool changes published by the scheduler to subscribed clients until ctx is cancelled
+func StartPoolListener(ctx context.Context) {
+	log.Logger.Info("PoolListener start")
+	for {
+		err := db.RedisSubscribe(ctx, handlePoolChange, models.PoolChangeChannel)
+		if ctx.Err() != nil {
+			log.Logger.Info("PoolListener stop")
+			return
+		}
+		log.Logger.Sugar().Error("pool change subscription closed ", err)
+		select {
+		case <-time.After(time.Second * 3):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func handlePoolChange(channel string, data []byte) {
+	event := models.PoolChangeEvent{}
+	if err := json.Unmarshal(data, &event); err != nil {
+		log.Logger.Sugar().Error("pool change unmarshal err ", err)
+		return
+	}
+	Manager.Publish(PoolTopic(event.ChainId, event.PoolId), event)
+}
@@ -0,0 +1,91 @@
+package ws
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ProtocolVersion version of the json message envelope
+const ProtocolVersion = 1
+
+// MaxTopicsPerClient upper bound of subscriptions a single connection may hold
+const MaxTopicsPerClient = 50
+
+// message types
+const (
+	TypeSubscribe   = "subscribe"
+	TypeUnsubscribe = "unsubscribe"
+	TypePing        = "ping"
+	TypePong        = "pong"
+	TypeAck         = "ack"
+	TypeEvent       = "event"
+	TypeError       = "error"
+)
+
+// topic prefixes
+const (
+	TopicPrice = "price"
+	TopicPool  = "pool"
+	TopicUser  = "user"
+)
+
+var priceTopicRegexp = regexp.MustCompile(`^price:[A-Z0-9]{1,20}-[A-Z0-9]{1,20}$`)
+var poolTopicRegexp = regexp.MustCompile(`^pool:(97|56):[0-9]{1,10}$`)
+var userTopicRegexp = regexp.MustCompile(`^user:0x[0-9a-f]{40}$`)
+
+// ClientMessage message sent by the browser
+type ClientMessage struct {
+	V      int      `json:"v"`
+	Id     string   `json:"id,omitempty"`
+	Type   string   `json:"type"`
+	Topics []string `json:"topics,omitempty"`
+}
+
+// Envelope message sent to the browser
+type Envelope struct {
+	V       int         `json:"v"`
+	Id      string      `json:"id,omitempty"`
+	Type    string      `json:"type"`
+	Topic   string      `json:"topic,omitempty"`
+	Topics  []string    `json:"topics,omitempty"`
+	Code    int         `json:"code,omitempty"`
+	Message string      `json:"message,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+	Ts      int64       `json:"ts"` // ms
+}
+
+// PriceTopic price:PLGR-USDT
+func PriceTopic(symbol string) string {
+	return TopicPrice + ":" + strings.ToUpper(symbol)
+}
+
+// PoolTopic pool:97:3
+func PoolTopic(chainId string, poolId string) string {
+	return fmt.Sprintf("%s:%s:%s", TopicPool, chainId, poolId)
+}
+
+// UserTopic user:0xabc..., addresses are matched case-insensitively
+func UserTopic(address string) string {
+	return TopicUser + ":" + strings.ToLower(address)
+}
+
+// NormalizeTopic returns the canonical form of topic, false if it is not a known topic
+func NormalizeTopic(topic string) (string, bool) {
+	parts := strings.SplitN(strings.TrimSpace(topic), ":", 2)
+	if len(parts) != 2 {
+		return "", false
+	}
+	switch parts[0] {
+	case TopicPrice:
+		topic = PriceTopic(parts[1])
+		return topic, priceTopicRegexp.MatchString(topic)
+	case TopicPool:
+		topic = TopicPool + ":" + parts[1]
+		return topic, poolTopicRegexp.MatchString(topic)
+	case TopicUser:
+		topic = UserTopic(parts[1])
+		return topic, userTopicRegexp.MatchString(topic)
+	}
+	return "", false
+}
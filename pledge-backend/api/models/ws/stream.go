@@ -0,0 +1,210 @@
+package ws
+
+import (
+	"fmt"
+	"net/http"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models/kucoin"
+	"pledge-backend/config"
+	"pledge-backend/log"
+	"pledge-backend/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StreamHeartbeat interval of the sse comment keeping proxies from closing an idle stream
+const StreamHeartbeat = 15 * time.Second
+
+// Stream server-sent events subscriber, fed by the same hub as the websocket servers.
+// Topics are fixed when the request is made.
+type Stream struct {
+	Id          string
+	Ip          string
+	Send        chan record // drained by Serve, never closed
+	ConnectedAt time.Time
+
+	topics  map[string]struct{}
+	epoch   int64 // of the hub the stream attached to, prefixes every event id
+	resumed bool  // Last-Event-ID was replayed, set by the hub before Serve runs
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewStream validate the requested topics, returns a statecode when they are refused
+func NewStream(id, ip string, topics []string) (*Stream, int) {
+	if len(topics) == 0 {
+		return nil, statecode.WsTopicErr
+	}
+	size := config.Config.Env.WssSendBufferSize
+	if size <= 0 {
+		size = 256
+	}
+	st := &Stream{
+		Id:          id,
+		Ip:          ip,
+		Send:        make(chan record, size),
+		ConnectedAt: time.Now(),
+		topics:      map[string]struct{}{},
+		done:        make(chan struct{}),
+	}
+	for _, t := range topics {
+		topic, ok := NormalizeTopic(t)
+		if !ok {
+			return nil, statecode.WsTopicErr
+		}
+		st.topics[topic] = struct{}{}
+	}
+	if len(st.topics) > MaxTopicsPerClient {
+		return nil, statecode.WsTooManyTopics
+	}
+	return st, statecode.CommonSuccess
+}
+
+// EventId sse event id "<epoch>-<seq>", seq counts the publications of the hub started at epoch
+// and begins at 1 again after a restart, so an id is only resumed by the hub that wrote it
+type EventId struct {
+	Epoch int64
+	Seq   uint64
+}
+
+func (id EventId) String() string {
+	return strconv.FormatInt(id.Epoch, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+// ParseLastEventId read the resume position, the zero EventId when absent or malformed
+func ParseLastEventId(r *http.Request) EventId {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("lastEventId") // EventSource can not set headers on the first request
+	}
+	parts := strings.SplitN(strings.TrimSpace(v), "-", 2)
+	if len(parts) != 2 {
+		return EventId{}
+	}
+	epoch, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return EventId{}
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return EventId{}
+	}
+	return EventId{Epoch: epoch, Seq: seq}
+}
+
+// IsSubscribed whether the stream asked for topic
+func (st *Stream) IsSubscribed(topic string) bool {
+	_, ok := st.topics[topic]
+	return ok
+}
+
+// enqueue never blocks, false when the queue is full
+func (st *Stream) enqueue(r record) bool {
+	select {
+	case <-st.done:
+		return true
+	default:
+	}
+	select {
+	case st.Send <- r:
+		return true
+	default:
+		return false
+	}
+}
+
+func (st *Stream) close() {
+	st.closeOnce.Do(func() {
+		close(st.done)
+	})
+}
+
+// Serve write events to w until the client goes away or the hub evicts the stream.
+// Attach must have succeeded before calling Serve.
+func (st *Stream) Serve(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		Manager.Detach <- st
+	}()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Logger.Sugar().Error(st.Id, " stream unsupported, response writer can not flush")
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no") // nginx
+	w.WriteHeader(http.StatusOK)
+
+	// reconnect delay hint for EventSource
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", 3000); err != nil {
+		return
+	}
+	// a new client, or one whose position this process can not replay (restart, too far behind)
+	if !st.resumed {
+		st.writeSnapshot(w)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(StreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case rec := <-st.Send:
+			if err := writeEvent(w, EventId{Epoch: st.epoch, Seq: rec.Seq}.String(), rec.Topic, rec.Data); err != nil {
+				log.Logger.Sugar().Info(st.Id, " stream write err ", err)
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-st.done:
+			return
+		}
+	}
+}
+
+// writeSnapshot latest known prices, sent without an id so they never move the resume position
+func (st *Stream) writeSnapshot(w http.ResponseWriter) {
+	for t := range st.topics {
+		if !strings.HasPrefix(t, TopicPrice+":") {
+			continue
+		}
+		price, _, ok := kucoin.GetPrice(strings.TrimPrefix(t, TopicPrice+":"))
+		if !ok {
+			continue
+		}
+		data, err := marshalEvent(t, price)
+		if err != nil {
+			continue
+		}
+		_ = writeEvent(w, "", t, data)
+	}
+}
+
+// writeEvent one sse event, the event name is the topic prefix (price, pool, user)
+func writeEvent(w http.ResponseWriter, id string, topic string, data []byte) error {
+	event := strings.SplitN(topic, ":", 2)[0]
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	metrics.WsMessages.WithLabelValues("sse", "out").Inc()
+	return nil
+}
@@ -1,13 +1,18 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/gorilla/websocket"
+	"pledge-backend/api/common/statecode"
 	"pledge-backend/api/models/kucoin"
 	"pledge-backend/config"
 	"pledge-backend/log"
+	"pledge-backend/metrics"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,87 +20,295 @@ const SuccessCode = 0
 const PongCode = 1
 const ErrorCode = -1
 
+const writeWait = 5 * time.Second
+const maxMessageSize = 4096
+
 type Server struct {
-	sync.Mutex
-	Id       string
-	Socket   *websocket.Conn
-	Send     chan []byte
-	LastTime int64 // last send time
-}
+	Id          string
+	Ip          string
+	Socket      *websocket.Conn
+	Send        chan []byte // drained by writePump, never closed
+	LastTime    int64       // last receive time, atomic
+	ConnectedAt time.Time
+
+	topicLock sync.RWMutex
+	topics    map[string]struct{}
+	versioned int32 // client speaks the json protocol, atomic
 
-type ServerManager struct {
-	Servers    sync.Map
-	Broadcast  chan []byte
-	Register   chan *Server
-	Unregister chan *Server
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
+// Message legacy frame, used for clients that only send the literal "ping"
 type Message struct {
 	Code int    `json:"code"`
 	Data string `json:"data"`
 }
 
-var Manager = ServerManager{}
-var UserPingPongDurTime = config.Config.Env.WssTimeoutDuration // seconds
+// LegacyPriceTopic every client that never sent a json message gets this topic as {"code":0,"data":"<price>"},
+// the way prices were pushed before topics existed
+var LegacyPriceTopic = PriceTopic("PLGR-USDT")
 
-func (s *Server) SendToClient(data string, code int) {
-	s.Lock()
-	defer s.Unlock()
+// marshalLegacyPrice the legacy frame of a LegacyPriceTopic publication
+func marshalLegacyPrice(data interface{}) ([]byte, error) {
+	update, ok := data.(kucoin.PriceUpdate)
+	if !ok {
+		return nil, errors.New("unexpected price update")
+	}
+	return json.Marshal(Message{Code: SuccessCode, Data: update.Price})
+}
 
+// NewServer wrap an upgraded connection
+func NewServer(id, ip string, conn *websocket.Conn) *Server {
+	size := config.Config.Env.WssSendBufferSize
+	if size <= 0 {
+		size = 256
+	}
+	return &Server{
+		Id:          id,
+		Ip:          ip,
+		Socket:      conn,
+		Send:        make(chan []byte, size),
+		LastTime:    time.Now().Unix(),
+		ConnectedAt: time.Now(),
+		topics:      map[string]struct{}{},
+		done:        make(chan struct{}),
+	}
+}
+
+func (s *Server) SendToClient(data string, code int) {
 	dataBytes, err := json.Marshal(Message{
 		Code: code,
 		Data: data,
 	})
-	err = s.Socket.WriteMessage(websocket.TextMessage, dataBytes)
 	if err != nil {
 		log.Logger.Sugar().Error(s.Id+" SendToClient err ", err)
+		return
 	}
+	s.reply(dataBytes)
 }
 
-func (s *Server) ReadAndWrite() {
+// SendEnvelope send a versioned json frame
+func (s *Server) SendEnvelope(env Envelope) {
+	env.V = ProtocolVersion
+	env.Ts = time.Now().UnixNano() / 1e6
+	dataBytes, err := json.Marshal(env)
+	if err != nil {
+		log.Logger.Sugar().Error(s.Id+" SendEnvelope err ", err)
+		return
+	}
+	s.reply(dataBytes)
+}
 
-	errChan := make(chan error)
+// SendError send an error frame with a statecode
+func (s *Server) SendError(id string, code int) {
+	s.SendEnvelope(Envelope{
+		Id:      id,
+		Type:    TypeError,
+		Code:    code,
+		Message: statecode.GetMsg(code, statecode.LangEn),
+	})
+}
 
-	Manager.Servers.Store(s.Id, s)
+// reply queue a frame from the connection's own goroutines, evict when the queue is full
+func (s *Server) reply(data []byte) {
+	if !s.enqueue(data) {
+		log.Logger.Sugar().Warn(s.Id, " websocket evicted, slow consumer")
+		Manager.Unregister <- s
+	}
+}
 
-	defer func() {
-		Manager.Servers.Delete(s)
+// enqueue never blocks, false when the queue is full
+func (s *Server) enqueue(data []byte) bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+	}
+	select {
+	case s.Send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// close send a close frame and release the socket, safe to call more than once
+func (s *Server) close(code int, reason string) {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		_ = s.Socket.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeWait))
 		_ = s.Socket.Close()
-		close(s.Send)
-	}()
+	})
+}
 
-	//write
-	go func() {
-		for {
-			select {
-			case message, ok := <-s.Send:
-				if !ok {
-					errChan <- errors.New("write message error")
-					return
-				}
-				s.SendToClient(string(message), SuccessCode)
+// legacy whether the client never sent a json message
+func (s *Server) legacy() bool {
+	return atomic.LoadInt32(&s.versioned) == 0
+}
+
+func (s *Server) lastTime() int64 {
+	return atomic.LoadInt64(&s.LastTime)
+}
+
+// IsSubscribed whether the connection asked for topic
+func (s *Server) IsSubscribed(topic string) bool {
+	s.topicLock.RLock()
+	defer s.topicLock.RUnlock()
+	_, ok := s.topics[topic]
+	return ok
+}
+
+// Topics current subscriptions of the connection
+func (s *Server) Topics() []string {
+	s.topicLock.RLock()
+	defer s.topicLock.RUnlock()
+	topics := make([]string, 0, len(s.topics))
+	for t := range s.topics {
+		topics = append(topics, t)
+	}
+	return topics
+}
+
+// subscribe add topics, all or nothing
+func (s *Server) subscribe(topics []string) ([]string, int) {
+	normalized := make([]string, 0, len(topics))
+	for _, t := range topics {
+		topic, ok := NormalizeTopic(t)
+		if !ok {
+			return nil, statecode.WsTopicErr
+		}
+		normalized = append(normalized, topic)
+	}
+
+	s.topicLock.Lock()
+	defer s.topicLock.Unlock()
+	added := 0
+	for _, t := range normalized {
+		if _, ok := s.topics[t]; !ok {
+			added++
+		}
+	}
+	if len(s.topics)+added > MaxTopicsPerClient {
+		return nil, statecode.WsTooManyTopics
+	}
+	for _, t := range normalized {
+		s.topics[t] = struct{}{}
+	}
+	return normalized, statecode.CommonSuccess
+}
+
+func (s *Server) unsubscribe(topics []string) []string {
+	s.topicLock.Lock()
+	defer s.topicLock.Unlock()
+	removed := make([]string, 0, len(topics))
+	for _, t := range topics {
+		if topic, ok := NormalizeTopic(t); ok {
+			delete(s.topics, topic)
+			removed = append(removed, topic)
+		}
+	}
+	return removed
+}
+
+// handleMessage dispatch one frame read from the client
+func (s *Server) handleMessage(message []byte) {
+	raw := strings.TrimSpace(string(message))
+
+	//legacy heartbeat
+	if raw == "ping" || raw == `"ping"` || raw == "'ping'" {
+		atomic.StoreInt64(&s.LastTime, time.Now().Unix())
+		s.SendToClient("pong", PongCode)
+		return
+	}
+
+	msg := ClientMessage{}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		s.SendError("", statecode.WsMessageErr)
+		return
+	}
+	atomic.StoreInt32(&s.versioned, 1)
+	atomic.StoreInt64(&s.LastTime, time.Now().Unix())
+
+	switch msg.Type {
+	case TypePing:
+		s.SendEnvelope(Envelope{Id: msg.Id, Type: TypePong})
+	case TypeSubscribe:
+		if len(msg.Topics) == 0 {
+			s.SendError(msg.Id, statecode.WsTopicErr)
+			return
+		}
+		topics, code := s.subscribe(msg.Topics)
+		if code != statecode.CommonSuccess {
+			s.SendError(msg.Id, code)
+			return
+		}
+		s.SendEnvelope(Envelope{Id: msg.Id, Type: TypeAck, Topics: topics})
+		s.sendSnapshot(topics)
+	case TypeUnsubscribe:
+		s.SendEnvelope(Envelope{Id: msg.Id, Type: TypeAck, Topics: s.unsubscribe(msg.Topics)})
+	default:
+		s.SendError(msg.Id, statecode.WsUnknownTypeErr)
+	}
+}
+
+// sendSnapshot push the latest known price right after subscribing
+func (s *Server) sendSnapshot(topics []string) {
+	for _, t := range topics {
+		if !strings.HasPrefix(t, TopicPrice+":") {
+			continue
+		}
+		price, _, ok := kucoin.GetPrice(strings.TrimPrefix(t, TopicPrice+":"))
+		if ok {
+			s.SendEnvelope(Envelope{Type: TypeEvent, Topic: t, Data: price})
+		}
+	}
+}
+
+// writePump the only goroutine writing data frames to the socket
+func (s *Server) writePump() {
+	for {
+		select {
+		case message := <-s.Send:
+			_ = s.Socket.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := s.Socket.WriteMessage(websocket.TextMessage, message); err != nil {
+				log.Logger.Sugar().Error(s.Id+" write err ", err)
+				Manager.Unregister <- s
+				return
 			}
+			metrics.WsMessages.WithLabelValues("websocket", "out").Inc()
+		case <-s.done:
+			return
 		}
+	}
+}
+
+func (s *Server) ReadAndWrite() {
+
+	errChan := make(chan error, 1)
+
+	Manager.Register <- s
+
+	defer func() {
+		Manager.Unregister <- s
 	}()
 
+	//write
+	go s.writePump()
+
 	//read
 	go func() {
+		s.Socket.SetReadLimit(maxMessageSize)
 		for {
 
 			_, message, err := s.Socket.ReadMessage()
 			if err != nil {
-				log.Logger.Sugar().Error(s.Id+" ReadMessage err ", err)
 				errChan <- err
 				return
 			}
+			metrics.WsMessages.WithLabelValues("websocket", "in").Inc()
 
-			//update heartbeat time
-			if string(message) == "ping" || string(message) == `"ping"` || string(message) == "'ping'" {
-				s.LastTime = time.Now().Unix()
-				s.SendToClient("pong", PongCode)
-			}
-			continue
-
+			s.handleMessage(message)
 		}
 	}()
 
@@ -103,28 +316,39 @@ func (s *Server) ReadAndWrite() {
 	for {
 		select {
 		case <-time.After(time.Second):
-			if time.Now().Unix()-s.LastTime >= UserPingPongDurTime {
-				s.SendToClient("heartbeat timeout", ErrorCode)
+			if time.Now().Unix()-s.lastTime() >= config.Current().Env.WssTimeoutDuration {
+				if !s.legacy() {
+					s.SendError("", statecode.WsHeartbeatErr)
+				} else {
+					s.SendToClient("heartbeat timeout", ErrorCode)
+				}
+				// give the write pump a moment to flush the timeout frame
+				time.Sleep(100 * time.Millisecond)
 				return
 			}
 		case err := <-errChan:
-			log.Logger.Sugar().Error(s.Id, " ReadAndWrite returned ", err)
+			log.Logger.Sugar().Info(s.Id, " ReadAndWrite returned ", err)
+			return
+		case <-s.done:
 			return
 		}
 	}
 }
 
-func StartServer() {
+// StartServer run the hub and forward exchange prices until ctx is cancelled,
+// the hub keeps running so Shutdown can drain the connections
+func StartServer(ctx context.Context) {
 	log.Logger.Info("WsServer start")
+	go Manager.Run()
 	for {
 		select {
-		case price, ok := <-kucoin.PlgrPriceChan:
+		case update, ok := <-kucoin.PriceChan:
 			if ok {
-				Manager.Servers.Range(func(key, value interface{}) bool {
-					value.(*Server).SendToClient(price, SuccessCode)
-					return true
-				})
+				Manager.Publish(PriceTopic(update.Symbol), update)
 			}
+		case <-ctx.Done():
+			log.Logger.Info("WsServer stop")
+			return
 		}
 	}
 }
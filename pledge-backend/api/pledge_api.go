@@ -1,6 +1,10 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"os/signal"
 	"pledge-backend/api/middlewares"
 	"pledge-backend/api/models"
 	"pledge-backend/api/models/kucoin"
@@ -10,12 +14,26 @@ import (
 	"pledge-backend/api/validate"
 	"pledge-backend/config"
 	"pledge-backend/db"
+	"pledge-backend/log"
+	"pledge-backend/tracing"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
 
+	// root context, cancelled on SIGTERM / SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	// reload live settings when the config file changes or on SIGHUP
+	go config.Watch(ctx, log.Logger.Sugar())
+
+	// tracer provider, see [tracing]
+	shutdownTracing := tracing.Init("pledge-api")
+
 	//init mysql
 	db.InitMysql()
 
@@ -27,21 +45,39 @@ func main() {
 	validate.BindingValidator() // 一个自定义验证器的初始化调用，用于绑定到框架（如Gin）的验证器，实现对请求参数的自动验证。
 
 	// websocket server
-	go ws.StartServer()
+	go ws.StartServer(ctx)
+
+	// pool changes pushed by the scheduler through redis
+	go ws.StartPoolListener(ctx)
 
 	// get plgr price from kucoin-exchange
-	go kucoin.GetExchangePrice() // 获取 KuCoin 交易所实时价格数据的函数调用，通常用于获取加密货币的当前交易价格信息。
+	go kucoin.GetExchangePrice(ctx) // 获取 KuCoin 交易所实时价格数据的函数调用，通常用于获取加密货币的当前交易价格信息。
 
 	// gin start
 	gin.SetMode(gin.ReleaseMode) // 设置Gin运行模式为发布模式（禁用调试信息）
 
-	// 创建默认Gin应用实例（包含Logger和Recovery中间件）
-	app := gin.Default()
+	// 创建Gin应用实例，只带Recovery，访问日志由 RequestLog 输出
+	app := gin.New()
+	app.Use(gin.Recovery())
+
+	// 只信任 env.trusted_proxies 转发的 X-Forwarded-For，否则 ClientIP 是连接的对端地址，限流、审计、登录记录都依赖它
+	if err := app.SetTrustedProxies(config.Config.Env.TrustedProxies); err != nil {
+		panic("trusted proxies err " + err.Error())
+	}
 
 	// 获取静态文件目录路径并设置静态文件路由
 	staticPath := static.GetCurrentAbPathByCaller()
 	app.Static("/storage/", staticPath)
 
+	// 每个请求一个 span，下游的 sql、redis、rpc 调用挂在其下
+	app.Use(middlewares.Trace())
+
+	// 请求 id 及带 id、路由、ip 的日志，每个请求一行访问日志
+	app.Use(middlewares.RequestLog())
+
+	// 请求计数及耗时，按路由和状态码统计
+	app.Use(middlewares.Metrics())
+
 	// 使用跨域中间件
 	app.Use(middlewares.Cors())
 
@@ -49,11 +85,37 @@ func main() {
 	routes.InitRoute(app)
 
 	// 启动HTTP服务，监听配置文件中指定的端口
-	_ = app.Run(":" + config.Config.Env.Port)
-
+	srv := &http.Server{
+		Addr:    ":" + config.Config.Env.Port,
+		Handler: app,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Logger.Sugar().Error("http server err ", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	log.Logger.Info("api shutting down")
+
+	// websockets and sse streams are hijacked or long lived, close them first so Shutdown only waits for ordinary requests
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Config.Env.ShutdownTimeout)*time.Second)
+	defer cancel()
+	if err := ws.Manager.Shutdown(shutdownCtx); err != nil {
+		log.Logger.Sugar().Warn("websocket drain err ", err)
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Logger.Sugar().Warn("http server shutdown err ", err)
+	}
+	db.Close()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Logger.Sugar().Warn("tracing flush err ", err)
+	}
+	log.Logger.Info("api stopped")
 }
 
 /*
- If you change the version, you need to modify the following files'
- config/init.go
+ The config file is config/configV21.toml unless --config <file> or PLEDGE_CONFIG names another one,
+ e.g. config/configV22.toml for version 22, see config/env.go for PLEDGE_* overrides
 */
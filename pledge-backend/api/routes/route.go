@@ -3,7 +3,9 @@ package routes
 import (
 	"pledge-backend/api/controllers"
 	"pledge-backend/api/middlewares"
+	"pledge-backend/api/models"
 	"pledge-backend/config"
+	"pledge-backend/metrics"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,29 +13,84 @@ import (
 // InitRoute 初始化应用路由
 func InitRoute(e *gin.Engine) *gin.Engine {
 
+	// probes for load balancers and systemd / 负载均衡探活，不限流
+	statusController := controllers.StatusController{}
+	e.GET("/healthz", statusController.Healthz) // liveness / 存活检查
+	e.GET("/readyz", statusController.Readyz)   // mysql and redis reachable / 就绪检查
+
+	// prometheus scrape, keep it off the public proxy / Prometheus 指标，不对外暴露
+	e.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	// version group / 版本分组路由
 	v2Group := e.Group("/api/v" + config.Config.Env.Version)
 
+	// rate limited groups / 限流分组，配额见 [ratelimit.groups]；auth、admin 分组的写操作记录审计日志
+	publicGroup := v2Group.Group("", middlewares.RateLimit(middlewares.RateLimitPublic))
+	searchGroup := v2Group.Group("", middlewares.RateLimit(middlewares.RateLimitSearch))
+	authGroup := v2Group.Group("", middlewares.RateLimit(middlewares.RateLimitAuth), middlewares.Audit())
+	adminGroup := v2Group.Group("", middlewares.RateLimit(middlewares.RateLimitAdmin), middlewares.Audit())
+
 	// pledge-defi backend / 质押DeFi后端接口
 	poolController := controllers.PoolController{}
-	v2Group.GET("/poolBaseInfo", poolController.PoolBaseInfo)                                   //pool base information / 资金池基础信息
-	v2Group.GET("/poolDataInfo", poolController.PoolDataInfo)                                   //pool data information / 资金池数据信息
-	v2Group.GET("/token", poolController.TokenList)                                             //pool token information / 资金池代币信息
-	v2Group.POST("/pool/debtTokenList", middlewares.CheckToken(), poolController.DebtTokenList) //pool debtTokenList / 债务代币列表（需令牌验证）
-	v2Group.POST("/pool/search", middlewares.CheckToken(), poolController.Search)               //pool search / 资金池搜索（需令牌验证）
+	publicGroup.GET("/poolBaseInfo", poolController.PoolBaseInfo)                                                                                       //pool base information / 资金池基础信息
+	publicGroup.GET("/poolDataInfo", poolController.PoolDataInfo)                                                                                       //pool data information / 资金池数据信息
+	publicGroup.GET("/token", poolController.TokenList)                                                                                                 //pool token information / 资金池代币信息
+	searchGroup.POST("/pool/debtTokenList", middlewares.CheckToken(), middlewares.RequirePermission(models.PermPoolRead), poolController.DebtTokenList) //pool debtTokenList / 债务代币列表（需令牌验证）
+	searchGroup.POST("/pool/search", middlewares.CheckToken(), middlewares.RequirePermission(models.PermPoolRead), poolController.Search)               //pool search / 资金池搜索（需令牌验证）
 
 	// plgr-usdt price / PLGR-USDT价格接口
 	priceController := controllers.PriceController{}
-	v2Group.GET("/price", priceController.NewPrice) //new price on ku-coin-exchange / 获取KuCoin交易所最新价格
+	publicGroup.GET("/price", priceController.NewPrice)                                                                                           //new price on ku-coin-exchange / 获取KuCoin交易所最新价格
+	publicGroup.GET("/price/stream", priceController.Stream)                                                                                      //price and pool events over sse / SSE推送价格及资金池变动
+	adminGroup.GET("/price/wsMetrics", middlewares.CheckToken(), middlewares.RequirePermission(models.PermSystemRead), priceController.WsMetrics) //websocket clients / WebSocket连接统计（需令牌验证）
+
+	// dependency status / 依赖状态
+	adminGroup.GET("/status", middlewares.CheckToken(), middlewares.RequirePermission(models.PermSystemRead), statusController.Status) // mysql, redis, rpc, scheduler and price feed / 各依赖状态（需令牌验证）
 
 	// pledge-defi admin backend / 质押DeFi管理后台接口
 	multiSignPoolController := controllers.MultiSignPoolController{}
-	v2Group.POST("/pool/setMultiSign", middlewares.CheckToken(), multiSignPoolController.SetMultiSign) //multi-sign set / 设置多重签名（需令牌验证）
-	v2Group.POST("/pool/getMultiSign", middlewares.CheckToken(), multiSignPoolController.GetMultiSign) //multi-sign get / 获取多重签名（需令牌验证）
+	adminGroup.POST("/pool/setMultiSign", middlewares.CheckToken(), middlewares.RequirePermission(models.PermMultiSignWrite), multiSignPoolController.SetMultiSign)        //multi-sign set / 设置多重签名（需令牌验证）
+	adminGroup.POST("/pool/getMultiSign", middlewares.CheckToken(), middlewares.RequirePermission(models.PermMultiSignRead), multiSignPoolController.GetMultiSign)         //multi-sign get / 获取多重签名（需令牌验证）
+	adminGroup.POST("/pool/multiSignHistory", middlewares.CheckToken(), middlewares.RequirePermission(models.PermMultiSignRead), multiSignPoolController.MultiSignHistory) //multi-sign revisions / 多重签名历史版本（需令牌验证）
 
 	userController := controllers.UserController{}
-	v2Group.POST("/user/login", userController.Login)                             // login / 用户登录
-	v2Group.POST("/user/logout", middlewares.CheckToken(), userController.Logout) // logout / 用户登出（需令牌验证）
+	authGroup.POST("/user/login", userController.Login)                                            // login / 用户登录
+	authGroup.GET("/user/siwe/nonce", userController.SiweNonce)                                    // sign-in with ethereum nonce / 钱包登录nonce
+	authGroup.POST("/user/siwe/login", userController.SiweLogin)                                   // sign-in with ethereum / 钱包签名登录
+	authGroup.POST("/user/refresh", userController.Refresh)                                        // refresh tokens / 刷新令牌
+	adminGroup.POST("/user/logout", middlewares.CheckToken(), userController.Logout)               // logout / 用户登出（需令牌验证）
+	adminGroup.POST("/user/logoutAll", middlewares.CheckToken(), userController.LogoutAll)         // logout everywhere / 退出全部会话（需令牌验证）
+	adminGroup.GET("/user/sessions", middlewares.CheckToken(), userController.Sessions)            // own sessions / 会话列表（需令牌验证）
+	adminGroup.POST("/user/revokeSession", middlewares.CheckToken(), userController.RevokeSession) // revoke a session / 注销指定会话（需令牌验证）
+
+	// admin accounts / 管理员账号管理
+	adminController := controllers.AdminController{}
+	adminGroup.GET("/admin/list", middlewares.CheckToken(), middlewares.RequirePermission(models.PermAdminRead), adminController.List)                     // admin list / 管理员列表（需令牌验证）
+	adminGroup.POST("/admin/create", middlewares.CheckToken(), middlewares.RequirePermission(models.PermAdminWrite), adminController.Create)               // create admin / 创建管理员（需令牌验证）
+	adminGroup.POST("/admin/setStatus", middlewares.CheckToken(), middlewares.RequirePermission(models.PermAdminWrite), adminController.SetStatus)         // enable or disable admin / 启用或停用管理员（需令牌验证）
+	adminGroup.POST("/admin/password", middlewares.CheckToken(), adminController.ChangePassword)                                                           // change own password / 修改自己的密码（需令牌验证）
+	adminGroup.POST("/admin/resetPassword", middlewares.CheckToken(), middlewares.RequirePermission(models.PermAdminWrite), adminController.ResetPassword) // reset admin password / 重置管理员密码（需令牌验证）
+	adminGroup.POST("/admin/setRole", middlewares.CheckToken(), middlewares.RequirePermission(models.PermAdminWrite), adminController.SetRole)             // change admin role / 修改管理员角色（需令牌验证）
+
+	// roles and permissions / 角色权限管理
+	rbacController := controllers.RbacController{}
+	adminGroup.GET("/rbac/roles", middlewares.CheckToken(), middlewares.RequirePermission(models.PermRbacRead), rbacController.Roles)                             // role permissions / 角色权限列表（需令牌验证）
+	adminGroup.POST("/rbac/setRolePermissions", middlewares.CheckToken(), middlewares.RequirePermission(models.PermRbacWrite), rbacController.SetRolePermissions) // edit role permissions / 修改角色权限（需令牌验证）
+
+	// integrator api keys / 对接方 API Key 管理
+	apiKeyController := controllers.ApiKeyController{}
+	adminGroup.GET("/apiKey/list", middlewares.CheckToken(), middlewares.RequirePermission(models.PermAdminRead), apiKeyController.List)       // api key list / API Key 列表（需令牌验证）
+	adminGroup.POST("/apiKey/create", middlewares.CheckToken(), middlewares.RequirePermission(models.PermAdminWrite), apiKeyController.Create) // issue api key / 创建 API Key（需令牌验证）
+	adminGroup.POST("/apiKey/revoke", middlewares.CheckToken(), middlewares.RequirePermission(models.PermAdminWrite), apiKeyController.Revoke) // revoke api key / 吊销 API Key（需令牌验证）
+
+	// log level / 日志级别
+	logController := controllers.LogController{}
+	adminGroup.GET("/system/logLevel", middlewares.CheckToken(), middlewares.RequirePermission(models.PermSystemRead), logController.Level)         // log level / 日志级别（需令牌验证）
+	adminGroup.POST("/system/setLogLevel", middlewares.CheckToken(), middlewares.RequirePermission(models.PermSystemWrite), logController.SetLevel) // change log level / 修改日志级别（需令牌验证）
+
+	// audit log / 审计日志
+	auditController := controllers.AuditController{}
+	adminGroup.GET("/audit/list", middlewares.CheckToken(), middlewares.RequirePermission(models.PermAuditRead), auditController.Query) // audit log / 审计日志查询（需令牌验证）
 
 	return e
 }
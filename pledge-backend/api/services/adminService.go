@@ -0,0 +1,259 @@
+package services
+
+import (
+	"errors"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models"
+	"pledge-backend/api/models/request"
+	"pledge-backend/log"
+	"pledge-backend/utils"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type AdminService struct {
+	audit *models.AuditLog
+}
+
+func NewAdmin() *AdminService {
+	return &AdminService{}
+}
+
+// Audit record changes into entry, the audit log of the request (nil when it is not audited)
+func (s *AdminService) Audit(entry *models.AuditLog) *AdminService {
+	s.audit = entry
+	return s
+}
+
+// dummyHash compared when the account does not exist so both cases take as long
+var dummyHash string
+var dummyHashOnce sync.Once
+
+func getDummyHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = utils.HashPassword(utils.GetRandomString(32))
+	})
+	return dummyHash
+}
+
+// Authenticate check name and password against the admin table
+func (s *AdminService) Authenticate(name string, password string) (int, *models.Admin) {
+	admin := models.NewAdmin()
+	err := admin.GetByName(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.CheckPasswordHash(password, getDummyHash())
+		return statecode.NameOrPasswordErr, nil
+	}
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr, nil
+	}
+	if !utils.CheckPasswordHash(password, admin.Password) {
+		return statecode.NameOrPasswordErr, nil
+	}
+	if !admin.Enabled() {
+		return statecode.AdminDisabledErr, nil
+	}
+	return statecode.CommonSuccess, admin
+}
+
+// Current the enabled admin called name, false when it does not exist or is disabled
+func (s *AdminService) Current(name string) (*models.Admin, bool) {
+	admin := models.NewAdmin()
+	if err := admin.GetByName(name); err != nil {
+		return nil, false
+	}
+	return admin, admin.Enabled()
+}
+
+func (s *AdminService) List() (int, []models.Admin) {
+	admins, err := models.NewAdmin().List()
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr, nil
+	}
+	return statecode.CommonSuccess, admins
+}
+
+func (s *AdminService) Create(operatorRole string, req *request.CreateAdmin) int {
+	if req.Role == models.RoleSuperAdmin && operatorRole != models.RoleSuperAdmin {
+		return statecode.PermissionErr
+	}
+	exists, err := models.NewAdmin().Exists(req.Name)
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
+	}
+	if exists {
+		return statecode.AdminExistErr
+	}
+	hash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
+	}
+	admin := models.NewAdmin()
+	if err = admin.Create(req.Name, hash, req.Role); err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
+	}
+	s.audit.Record("admin", req.Name, nil, admin)
+	return statecode.CommonSuccess
+}
+
+// SetStatus enable or disable an admin, disabling also ends its login
+func (s *AdminService) SetStatus(operator string, operatorRole string, req *request.SetAdminStatus) int {
+	if req.Name == operator && *req.Status == models.AdminDisabled {
+		return statecode.AdminSelfErr
+	}
+	target, code := s.target(operatorRole, req.Name)
+	if code != statecode.CommonSuccess {
+		return code
+	}
+	if target.Role == models.RoleSuperAdmin && *req.Status == models.AdminDisabled {
+		if code = s.keepSuperAdmin(); code != statecode.CommonSuccess {
+			return code
+		}
+	}
+	if err := models.NewAdmin().SetStatus(req.Name, *req.Status); err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
+	}
+	after := *target
+	after.Status = *req.Status
+	s.audit.Record("admin", req.Name, target, after)
+	if *req.Status == models.AdminDisabled {
+		s.logout(req.Name)
+	}
+	return statecode.CommonSuccess
+}
+
+// SetRole change the role of an admin, its login ends so the next token carries the new role
+func (s *AdminService) SetRole(operatorRole string, req *request.SetAdminRole) int {
+	if req.Role == models.RoleSuperAdmin && operatorRole != models.RoleSuperAdmin {
+		return statecode.PermissionErr
+	}
+	target, code := s.target(operatorRole, req.Name)
+	if code != statecode.CommonSuccess {
+		return code
+	}
+	if target.Role == req.Role {
+		return statecode.CommonSuccess
+	}
+	if target.Role == models.RoleSuperAdmin {
+		if code = s.keepSuperAdmin(); code != statecode.CommonSuccess {
+			return code
+		}
+	}
+	if err := models.NewAdmin().SetRole(req.Name, req.Role); err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
+	}
+	after := *target
+	after.Role = req.Role
+	s.audit.Record("admin", req.Name, target, after)
+	s.logout(req.Name)
+	return statecode.CommonSuccess
+}
+
+// ChangePassword change the operator's own password
+func (s *AdminService) ChangePassword(operator string, req *request.ChangePassword) int {
+	code, _ := s.Authenticate(operator, req.OldPassword)
+	if code != statecode.CommonSuccess {
+		return code
+	}
+	s.audit.Record("admin", operator, nil, nil)
+	return s.setPassword(operator, req.NewPassword)
+}
+
+// ResetPassword set another admin's password and end its login
+func (s *AdminService) ResetPassword(operatorRole string, req *request.ResetPassword) int {
+	if _, code := s.target(operatorRole, req.Name); code != statecode.CommonSuccess {
+		return code
+	}
+	s.audit.Record("admin", req.Name, nil, nil)
+	if code := s.setPassword(req.Name, req.NewPassword); code != statecode.CommonSuccess {
+		return code
+	}
+	s.logout(req.Name)
+	return statecode.CommonSuccess
+}
+
+// logout end every session of name
+func (s *AdminService) logout(name string) {
+	if err := models.NewSession().RevokeAll(name); err != nil {
+		log.Logger.Error(err.Error())
+	}
+}
+
+func (s *AdminService) setPassword(name string, password string) int {
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
+	}
+	if err = models.NewAdmin().SetPassword(name, hash); err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
+	}
+	return statecode.CommonSuccess
+}
+
+// target load the admin an operator wants to change, only a superadmin may change a superadmin
+func (s *AdminService) target(operatorRole string, name string) (*models.Admin, int) {
+	admin := models.NewAdmin()
+	err := admin.GetByName(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, statecode.AdminNotExistErr
+	}
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return nil, statecode.CommonErrServerErr
+	}
+	if admin.Role == models.RoleSuperAdmin && operatorRole != models.RoleSuperAdmin {
+		return nil, statecode.PermissionErr
+	}
+	return admin, statecode.CommonSuccess
+}
+
+// keepSuperAdmin refuse to remove the last superadmin
+func (s *AdminService) keepSuperAdmin() int {
+	total, err := models.NewAdmin().CountRole(models.RoleSuperAdmin)
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
+	}
+	if total <= 1 {
+		return statecode.LastSuperAdminErr
+	}
+	return statecode.CommonSuccess
+}
+
+// Bootstrap create the [defaultadmin] account as superadmin when the admin table is empty,
+// or promote the oldest admin when no superadmin exists (tables created before roles)
+func (s *AdminService) Bootstrap(name string, password string) (string, error) {
+	total, err := models.NewAdmin().Count()
+	if err != nil {
+		return "", err
+	}
+	if total > 0 {
+		supers, err := models.NewAdmin().CountRole(models.RoleSuperAdmin)
+		if err != nil || supers > 0 {
+			return "", err
+		}
+		first := models.NewAdmin()
+		if err = first.First(); err != nil {
+			return "", err
+		}
+		return "promoted " + first.Name + " to " + models.RoleSuperAdmin, first.SetRole(first.Name, models.RoleSuperAdmin)
+	}
+	if name == "" || password == "" {
+		return "", errors.New("defaultadmin username and password must be set")
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return "", err
+	}
+	return "created " + name + ", change its password after the first login", models.NewAdmin().Create(name, hash, models.RoleSuperAdmin)
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models"
+	"pledge-backend/api/models/request"
+	"pledge-backend/api/models/response"
+	"pledge-backend/log"
+	"pledge-backend/utils"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// apiKeyPrefix marks keys issued by this backend
+const apiKeyPrefix = "pk_"
+
+// apiKeyTTL how long a looked up key is cached, a revoked key keeps working on other instances at most this long
+const apiKeyTTL = 30 * time.Second
+
+// apiKeyMissingMax unknown hashes remembered at most, the set starts over when it is full so random keys can not grow it
+const apiKeyMissingMax = 10000
+
+type apiKeyEntry struct {
+	key      *models.ApiKey // nil when the key does not exist
+	loadedAt time.Time
+}
+
+var apiKeyLock sync.Mutex
+var apiKeyCache = map[string]apiKeyEntry{} // existing keys, bounded by the keys issued
+var apiKeyMissing = map[string]time.Time{} // hashes without a key and when that was checked
+
+type ApiKeyService struct {
+	audit *models.AuditLog
+}
+
+func NewApiKey() *ApiKeyService {
+	return &ApiKeyService{}
+}
+
+// Audit record changes into entry, the audit log of the request (nil when it is not audited)
+func (s *ApiKeyService) Audit(entry *models.AuditLog) *ApiKeyService {
+	s.audit = entry
+	return s
+}
+
+// cached the entry of hash when it was loaded less than apiKeyTTL ago
+func cached(hash string) (apiKeyEntry, bool) {
+	apiKeyLock.Lock()
+	defer apiKeyLock.Unlock()
+	if entry, ok := apiKeyCache[hash]; ok && time.Since(entry.loadedAt) < apiKeyTTL {
+		return entry, true
+	}
+	if at, ok := apiKeyMissing[hash]; ok && time.Since(at) < apiKeyTTL {
+		return apiKeyEntry{loadedAt: at}, true
+	}
+	return apiKeyEntry{}, false
+}
+
+// Known whether plain is an active key this instance already has cached, Lookup of it does not touch the database
+func (s *ApiKeyService) Known(plain string) bool {
+	entry, ok := cached(utils.Sha256Hex(plain))
+	return ok && entry.key != nil && entry.key.Active()
+}
+
+// Lookup the active key matching plain, ApiKeyErr when it does not exist or was revoked
+func (s *ApiKeyService) Lookup(plain string) (*models.ApiKey, int) {
+	hash := utils.Sha256Hex(plain)
+
+	entry, ok := cached(hash)
+	if !ok {
+		key := models.NewApiKey()
+		err := key.GetByHash(hash)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Logger.Error(err.Error())
+			return nil, statecode.CommonErrServerErr
+		}
+		if err != nil {
+			key = nil
+		}
+		entry = apiKeyEntry{key: key, loadedAt: time.Now()}
+
+		apiKeyLock.Lock()
+		if key != nil {
+			apiKeyCache[hash] = entry
+			delete(apiKeyMissing, hash)
+		} else {
+			if len(apiKeyMissing) >= apiKeyMissingMax {
+				apiKeyMissing = map[string]time.Time{}
+			}
+			apiKeyMissing[hash] = entry.loadedAt
+			delete(apiKeyCache, hash)
+		}
+		apiKeyLock.Unlock()
+	}
+
+	if entry.key == nil || !entry.key.Active() {
+		return nil, statecode.ApiKeyErr
+	}
+	return entry.key, statecode.CommonSuccess
+}
+
+func (s *ApiKeyService) List() (int, []models.ApiKey) {
+	keys, err := models.NewApiKey().List()
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr, nil
+	}
+	return statecode.CommonSuccess, keys
+}
+
+// Create issue a key for an integrator, the plain key is only returned here
+func (s *ApiKeyService) Create(operator string, req *request.CreateApiKey) (int, response.ApiKey) {
+	secret, err := utils.RandomHex(24)
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr, response.ApiKey{}
+	}
+	plain := apiKeyPrefix + secret
+	key := models.NewApiKey()
+	if err = key.Create(req.Name, plain[:len(apiKeyPrefix)+8], utils.Sha256Hex(plain), operator); err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr, response.ApiKey{}
+	}
+	s.audit.Record("api_key", strconv.Itoa(key.Id), nil, key)
+	return statecode.CommonSuccess, response.ApiKey{
+		Id:     key.Id,
+		Name:   key.Name,
+		Prefix: key.Prefix,
+		Key:    plain,
+	}
+}
+
+// Revoke disable a key, this instance stops accepting it immediately
+func (s *ApiKeyService) Revoke(req *request.RevokeApiKey) int {
+	key := models.NewApiKey()
+	err := key.GetById(req.Id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return statecode.ApiKeyNotExistErr
+	}
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
+	}
+	if err = models.NewApiKey().Revoke(req.Id); err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
+	}
+	after := *key
+	after.Status = models.ApiKeyRevoked
+	s.audit.Record("api_key", strconv.Itoa(req.Id), key, after)
+
+	apiKeyLock.Lock()
+	delete(apiKeyCache, key.KeyHash)
+	apiKeyLock.Unlock()
+	return statecode.CommonSuccess
+}
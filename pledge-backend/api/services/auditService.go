@@ -0,0 +1,46 @@
+package services
+
+import (
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models"
+	"pledge-backend/api/models/request"
+	"pledge-backend/api/models/response"
+	"pledge-backend/log"
+	"time"
+)
+
+type AuditService struct{}
+
+func NewAudit() *AuditService {
+	return &AuditService{}
+}
+
+// Query audit log entries, newest first
+func (s *AuditService) Query(req *request.AuditQuery) (int, response.AuditLogs) {
+	filters := models.Filters{}
+	if req.Actor != "" {
+		filters = append(filters, models.Eq(models.ColAuditActor, req.Actor))
+	}
+	if req.EntityType != "" {
+		filters = append(filters, models.Eq(models.ColAuditEntityType, req.EntityType))
+	}
+	if req.EntityId != "" {
+		filters = append(filters, models.Eq(models.ColAuditEntityId, req.EntityId))
+	}
+	if req.Route != "" {
+		filters = append(filters, models.Eq(models.ColAuditRoute, req.Route))
+	}
+	if req.From > 0 {
+		filters = append(filters, models.Gte(models.ColAuditCreatedAt, time.Unix(req.From, 0)))
+	}
+	if req.To > 0 {
+		filters = append(filters, models.Lte(models.ColAuditCreatedAt, time.Unix(req.To, 0)))
+	}
+
+	logs, total, err := models.NewAuditLog().Query(filters, req.Page, req.PageSize)
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr, response.AuditLogs{}
+	}
+	return statecode.CommonSuccess, response.AuditLogs{Count: total, Rows: logs}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models"
+	"pledge-backend/api/models/request"
+	"pledge-backend/api/models/response"
+	"pledge-backend/config"
+	"pledge-backend/log"
+)
+
+type LogService struct {
+	audit *models.AuditLog
+}
+
+func NewLog() *LogService {
+	return &LogService{}
+}
+
+// Audit record changes into entry, the audit log of the request (nil when it is not audited)
+func (s *LogService) Audit(entry *models.AuditLog) *LogService {
+	s.audit = entry
+	return s
+}
+
+// Level current default level of this api process and the per package overrides
+func (s *LogService) Level() response.LogLevel {
+	return response.LogLevel{Level: log.Level.String(), Packages: config.Config.Log.Packages}
+}
+
+// SetLevel change the default level until the next restart, the scheduler is not affected
+func (s *LogService) SetLevel(req *request.SetLogLevel) int {
+	before := log.Level.String()
+	if err := log.SetLevel(req.Level); err != nil {
+		return statecode.LogLevelErr
+	}
+	log.Logger.Sugar().Warn("log level changed from ", before, " to ", req.Level)
+	s.audit.Record("log_level", "api", before, req.Level)
+	return statecode.CommonSuccess
+}
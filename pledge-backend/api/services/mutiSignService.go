@@ -1,48 +1,199 @@
 package services
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
 	"pledge-backend/api/common/statecode"
 	"pledge-backend/api/models"
+	"pledge-backend/api/models/chain"
 	"pledge-backend/api/models/request"
 	"pledge-backend/api/models/response"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-sql-driver/mysql"
+)
+
+// verification status of a multi-sign revision against the chain
+const (
+	VerificationMatch       = "match"
+	VerificationMismatch    = "mismatch"
+	VerificationUnavailable = "unavailable"
 )
 
-type MutiSignService struct{}
+type MutiSignService struct {
+	audit *models.AuditLog
+}
 
 func NewMutiSign() *MutiSignService {
 	return &MutiSignService{}
 }
 
-// SetMultiSign Set Multi-Sign
-func (c *MutiSignService) SetMultiSign(mutiSign *request.SetMultiSign) (int, error) {
+// Audit record changes into entry, the audit log of the request (nil when it is not audited)
+func (c *MutiSignService) Audit(entry *models.AuditLog) *MutiSignService {
+	c.audit = entry
+	return c
+}
+
+// SetMultiSign Set Multi-Sign, saved as a new revision once the chain confirms the sp/jp contracts and the owner list
+func (c *MutiSignService) SetMultiSign(ctx context.Context, mutiSign *request.SetMultiSign, operator string) (int, error) {
+	if code, err := c.checkChain(ctx, mutiSign); code != statecode.CommonSuccess {
+		return code, err
+	}
+
+	before := models.NewMultiSign()
+	if err := before.Get(mutiSign.ChainId); err != nil {
+		return statecode.CommonErrServerErr, err
+	}
+	effectiveFrom := time.Now()
+	if mutiSign.EffectiveFrom > effectiveFrom.Unix() {
+		effectiveFrom = time.Unix(mutiSign.EffectiveFrom, 0)
+	}
 	//db set
-	err := models.NewMultiSign().Set(mutiSign)
+	after := models.NewMultiSign()
+	err := after.Set(mutiSign, effectiveFrom, operator)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return statecode.MultiSignExistsErr, err
+	}
 	if err != nil {
 		return statecode.CommonErrServerErr, err
 	}
+	if before.Id == 0 {
+		c.audit.Record("multi_sign", strconv.Itoa(mutiSign.ChainId), nil, after)
+	} else {
+		c.audit.Record("multi_sign", strconv.Itoa(mutiSign.ChainId), before, after)
+	}
+	return statecode.CommonSuccess, nil
+}
+
+// checkChain sp/jp addresses are contracts and the owner list equals the multiSignature signer set
+func (c *MutiSignService) checkChain(ctx context.Context, mutiSign *request.SetMultiSign) (int, error) {
+	client, err := chain.Dial(ctx, mutiSign.ChainId)
+	if err != nil {
+		return statecode.ChainRpcErr, err
+	}
+	defer client.Close()
+
+	for _, address := range []string{mutiSign.SpAddress, mutiSign.JpAddress} {
+		deployed, err := client.IsContract(ctx, address)
+		if err != nil {
+			return statecode.ChainRpcErr, err
+		}
+		if !deployed {
+			return statecode.NotContractErr, fmt.Errorf("no contract at %s on chain %d", address, mutiSign.ChainId)
+		}
+	}
+
+	owners, _, err := client.SignatureOwners(ctx)
+	if err != nil {
+		return statecode.ChainRpcErr, err
+	}
+	missing, unexpected := compareOwners(mutiSign.MultiSignAccount, owners)
+	if len(missing) > 0 || len(unexpected) > 0 {
+		return statecode.OwnerMismatchErr, fmt.Errorf("owner list mismatch on chain %d, missing %v unexpected %v", mutiSign.ChainId, missing, unexpected)
+	}
 	return statecode.CommonSuccess, nil
 }
 
-// GetMultiSign Get Multi-Sign
-func (c *MutiSignService) GetMultiSign(mutiSign *response.MultiSign, chainId int) (int, error) {
+// compareOwners owners on chain not in accounts, and accounts not on chain
+func compareOwners(accounts []string, owners []common.Address) (missing []string, unexpected []string) {
+	submitted := map[common.Address]bool{}
+	for _, account := range accounts {
+		submitted[common.HexToAddress(account)] = true
+	}
+	onchain := map[common.Address]bool{}
+	for _, owner := range owners {
+		onchain[owner] = true
+		if !submitted[owner] {
+			missing = append(missing, owner.Hex())
+		}
+	}
+	for account := range submitted {
+		if !onchain[account] {
+			unexpected = append(unexpected, account.Hex())
+		}
+	}
+	sort.Strings(unexpected)
+	return missing, unexpected
+}
+
+// verify compare a stored owner list with the chain, never fails, an unreachable chain is reported as unavailable
+func (c *MutiSignService) verify(ctx context.Context, chainId int, accounts []string) *response.MultiSignVerification {
+	res := &response.MultiSignVerification{OnchainOwners: []string{}, Missing: []string{}, Unexpected: []string{}}
+	client, err := chain.Dial(ctx, chainId)
+	if err != nil {
+		res.Status, res.Error = VerificationUnavailable, err.Error()
+		return res
+	}
+	defer client.Close()
+
+	owners, threshold, err := client.SignatureOwners(ctx)
+	if err != nil {
+		res.Status, res.Error = VerificationUnavailable, err.Error()
+		return res
+	}
+	for _, owner := range owners {
+		res.OnchainOwners = append(res.OnchainOwners, owner.Hex())
+	}
+	res.Threshold = threshold
+	missing, unexpected := compareOwners(accounts, owners)
+	res.Missing = append(res.Missing, missing...)
+	res.Unexpected = append(res.Unexpected, unexpected...)
+	res.Status = VerificationMatch
+	if len(missing) > 0 || len(unexpected) > 0 {
+		res.Status = VerificationMismatch
+	}
+	return res
+}
+
+func multiSignResponse(m *models.MultiSign) response.MultiSign {
+	res := response.MultiSign{
+		SpName:           m.SpName,
+		SpToken:          m.SpToken,
+		JpName:           m.JpName,
+		JpToken:          m.JpToken,
+		SpAddress:        m.SpAddress,
+		JpAddress:        m.JpAddress,
+		SpHash:           m.SpHash,
+		JpHash:           m.JpHash,
+		MultiSignAccount: m.Accounts(),
+		Version:          m.Version,
+		CreatedBy:        m.CreatedBy,
+	}
+	if m.EffectiveFrom != nil {
+		res.EffectiveFrom = m.EffectiveFrom.Unix()
+	}
+	return res
+}
+
+// GetMultiSign Get Multi-Sign, the revision in effect with its owner list checked against the chain
+func (c *MutiSignService) GetMultiSign(ctx context.Context, mutiSign *response.MultiSign, chainId int) (int, error) {
 	//db get
 	multiSignModel := models.NewMultiSign()
 	err := multiSignModel.Get(chainId)
 	if err != nil {
 		return statecode.CommonErrServerErr, err
 	}
-	var multiSignAccount []string
-	_ = json.Unmarshal([]byte(multiSignModel.MultiSignAccount), &multiSignAccount)
-
-	mutiSign.SpName = multiSignModel.SpName
-	mutiSign.SpToken = multiSignModel.SpToken
-	mutiSign.JpName = multiSignModel.JpName
-	mutiSign.JpToken = multiSignModel.JpToken
-	mutiSign.SpAddress = multiSignModel.SpAddress
-	mutiSign.JpAddress = multiSignModel.JpAddress
-	mutiSign.SpHash = multiSignModel.SpHash
-	mutiSign.JpHash = multiSignModel.JpHash
-	mutiSign.MultiSignAccount = multiSignAccount
+	*mutiSign = multiSignResponse(multiSignModel)
+	if multiSignModel.Id != 0 {
+		mutiSign.Verification = c.verify(ctx, chainId, mutiSign.MultiSignAccount)
+	}
 	return statecode.CommonSuccess, nil
 }
+
+// History every revision of the chain, newest first
+func (c *MutiSignService) History(chainId int) (int, []response.MultiSign, error) {
+	revisions, err := models.NewMultiSign().History(chainId)
+	if err != nil {
+		return statecode.CommonErrServerErr, nil, err
+	}
+	res := make([]response.MultiSign, 0, len(revisions))
+	for i := range revisions {
+		res = append(res, multiSignResponse(&revisions[i]))
+	}
+	return statecode.CommonSuccess, res, nil
+}
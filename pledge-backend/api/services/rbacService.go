@@ -0,0 +1,88 @@
+package services
+
+import (
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models"
+	"pledge-backend/api/models/request"
+	"pledge-backend/log"
+	"pledge-backend/utils"
+	"sync"
+	"time"
+)
+
+// rolePermissionTTL how long the mapping is cached before it is read from the database again
+const rolePermissionTTL = 30 * time.Second
+
+var rolePermissionLock sync.Mutex
+var rolePermissionCache map[string][]string
+var rolePermissionLoadedAt time.Time
+
+type RbacService struct {
+	audit *models.AuditLog
+}
+
+func NewRbac() *RbacService {
+	return &RbacService{}
+}
+
+// Audit record changes into entry, the audit log of the request (nil when it is not audited)
+func (s *RbacService) Audit(entry *models.AuditLog) *RbacService {
+	s.audit = entry
+	return s
+}
+
+// RolePermissions current role to permissions mapping
+func (s *RbacService) RolePermissions() (map[string][]string, error) {
+	rolePermissionLock.Lock()
+	defer rolePermissionLock.Unlock()
+	if rolePermissionCache != nil && time.Since(rolePermissionLoadedAt) < rolePermissionTTL {
+		return rolePermissionCache, nil
+	}
+	mapping, err := models.NewRolePermission().All()
+	if err != nil {
+		return nil, err
+	}
+	rolePermissionCache = mapping
+	rolePermissionLoadedAt = time.Now()
+	return mapping, nil
+}
+
+// Permissions permissions currently granted to role
+func (s *RbacService) Permissions(role string) []string {
+	mapping, err := s.RolePermissions()
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return nil
+	}
+	return mapping[role]
+}
+
+// HasPermission whether role currently holds permission
+func (s *RbacService) HasPermission(role string, permission string) bool {
+	return utils.IsContain(permission, s.Permissions(role))
+}
+
+func (s *RbacService) invalidate() {
+	rolePermissionLock.Lock()
+	rolePermissionCache = nil
+	rolePermissionLock.Unlock()
+}
+
+// SetRolePermissions replace the permissions of a role, superadmin can not be edited
+func (s *RbacService) SetRolePermissions(req *request.SetRolePermissions) int {
+	if req.Role == models.RoleSuperAdmin {
+		return statecode.RoleErr
+	}
+	before, err := models.NewRolePermission().All()
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
+	}
+	if err := models.NewRolePermission().Set(req.Role, req.Permissions); err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
+	}
+	s.invalidate()
+	s.audit.Record("role", req.Role, before[req.Role], req.Permissions)
+	return statecode.CommonSuccess
+}
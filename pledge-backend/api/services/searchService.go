@@ -1,10 +1,11 @@
 package services
 
 import (
-	"fmt"
+	"context"
 	"pledge-backend/api/common/statecode"
 	"pledge-backend/api/models"
 	"pledge-backend/api/models/request"
+	"pledge-backend/api/models/response"
 	"pledge-backend/log"
 )
 
@@ -17,26 +18,39 @@ func NewSearch() *SearchService {
 }
 
 // Search 搜索资金池
-// 返回值：状态码, 总记录数, 资金池列表
-func (c *SearchService) Search(req *request.Search) (int, int64, []models.Pool) {
+// 返回值：状态码, 搜索结果（总数、当前页、下一页游标、可选的分面统计）
+func (c *SearchService) Search(ctx context.Context, req *request.Search) (int, response.Search) {
+	result := response.Search{Rows: []models.Pool{}}
 
-	// 构建SQL查询条件
-	whereCondition := fmt.Sprintf(`chain_id='%v'`, req.ChainID)
-	if req.LendTokenSymbol != "" {
-		whereCondition += fmt.Sprintf(` and lend_token_symbol='%v'`, req.LendTokenSymbol)
-	}
-	if req.State != "" {
-		whereCondition += fmt.Sprintf(` and state='%v'`, req.State)
+	sorts, ok := models.ParsePoolSorts(req.Sort)
+	if !ok {
+		return statecode.SearchParamErr, result
 	}
 
-	// 调用模型层进行分页查询（注意：Pagination返回顺序为：total, data, err）
-	total, pools, err := models.NewPool().Pagination(req, whereCondition)
+	// 请求参数转换为参数化查询条件
+	filters := models.PoolSearchFilters(req)
+
+	page, err := models.NewPool().Search(ctx, filters, sorts, req.Cursor, req.Page, req.PageSize)
+	if err == models.ErrInvalidCursor {
+		return statecode.SearchCursorErr, result
+	}
 	if err != nil {
 		// 查询失败，记录错误日志并返回错误状态码
 		log.Logger.Error(err.Error())
-		return statecode.CommonErrServerErr, 0, nil
+		return statecode.CommonErrServerErr, result
+	}
+	result.Count = page.Total
+	result.Rows = page.Rows
+	result.NextCursor = page.NextCursor
+
+	if req.Facets {
+		facets, err := models.NewPool().Facets(ctx, filters)
+		if err != nil {
+			log.Logger.Error(err.Error())
+			return statecode.CommonErrServerErr, result
+		}
+		result.Facets = &facets
 	}
 
-	// 查询成功，返回成功状态码、总记录数和数据
-	return statecode.CommonSuccess, total, pools
+	return statecode.CommonSuccess, result
 }
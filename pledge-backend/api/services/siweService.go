@@ -0,0 +1,126 @@
+package services
+
+import (
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models"
+	"pledge-backend/api/models/request"
+	"pledge-backend/api/models/response"
+	"pledge-backend/config"
+	"pledge-backend/db"
+	"pledge-backend/log"
+	"pledge-backend/utils"
+	"strconv"
+	"time"
+)
+
+// siweClockSkew how far in the future a message may be issued
+const siweClockSkew = time.Minute
+
+type SiweService struct {
+	audit *models.AuditLog
+}
+
+func NewSiwe() *SiweService {
+	return &SiweService{}
+}
+
+// Audit record changes into entry, the audit log of the request (nil when it is not audited)
+func (s *SiweService) Audit(entry *models.AuditLog) *SiweService {
+	s.audit = entry
+	return s
+}
+
+func siweNonceKey(nonce string) string {
+	return "siwe_nonce:" + nonce
+}
+
+// Nonce issue a single-use nonce for the next sign-in message
+func (s *SiweService) Nonce() (int, response.SiweNonce) {
+	nonce, err := utils.RandomHex(16)
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr, response.SiweNonce{}
+	}
+	if err = db.RedisSetString(siweNonceKey(nonce), "1", config.Config.Siwe.NonceTTL); err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr, response.SiweNonce{}
+	}
+	return statecode.CommonSuccess, response.SiweNonce{
+		Nonce:     nonce,
+		Domain:    config.Config.Siwe.Domain,
+		ChainIds:  siweChainIds(),
+		ExpiresIn: config.Config.Siwe.NonceTTL,
+	}
+}
+
+// siweChainIds chains a message may be signed for
+func siweChainIds() []int {
+	ids := []int{}
+	for _, id := range []string{config.Config.TestNet.ChainId, config.Config.MainNet.ChainId} {
+		if n, err := strconv.Atoi(id); err == nil {
+			ids = append(ids, n)
+		}
+	}
+	return ids
+}
+
+// Login verify a signed EIP-4361 message and start a session for the address
+func (s *SiweService) Login(req *request.SiweLogin, ip string, userAgent string, result *response.Login) int {
+	message, err := utils.ParseSiweMessage(req.Message)
+	if err != nil {
+		log.Logger.Sugar().Info("siwe ", err)
+		return statecode.SiweMessageErr
+	}
+	now := time.Now()
+	if message.Domain != config.Config.Siwe.Domain ||
+		!utils.IsContain(strconv.Itoa(message.ChainId), []string{config.Config.TestNet.ChainId, config.Config.MainNet.ChainId}) ||
+		message.IssuedAt.After(now.Add(siweClockSkew)) || !message.ValidAt(now) {
+		return statecode.SiweMessageErr
+	}
+
+	s.audit.SetActor(message.Address)
+	address, err := utils.RecoverPersonalSign(req.Message, req.Signature)
+	if err != nil || address != message.Address {
+		return statecode.SiweSignatureErr
+	}
+
+	// the nonce is consumed only by a valid signature, whoever deletes it first wins
+	if used, err := db.RedisDelete(siweNonceKey(message.Nonce)); err != nil || !used {
+		return statecode.SiweNonceErr
+	}
+
+	role, err := s.Role(message.ChainId, address)
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
+	}
+	log.Logger.Sugar().Info("siwe login ", address, " chain ", message.ChainId, " role ", role)
+
+	// wallet sessions are keyed by the checksummed address, admin names can not start with a digit
+	session := &models.Session{Address: address, ChainId: message.ChainId}
+	refreshToken, err := session.Create(address, ip, userAgent)
+	if err != nil {
+		log.Logger.Error("CreateSession" + err.Error())
+		return statecode.CommonErrServerErr
+	}
+	s.audit.Record("session", session.SessionId, nil, nil)
+	return NewUser().issue(utils.TokenClaims{
+		Username:  address,
+		Role:      role,
+		SessionId: session.SessionId,
+		Address:   address,
+		ChainId:   message.ChainId,
+	}, refreshToken, result)
+}
+
+// Role multi-sign owners of the chain get [siwe] owner_role, any other address RoleWallet
+func (s *SiweService) Role(chainId int, address string) (string, error) {
+	owner, err := models.NewMultiSign().IsOwner(chainId, address)
+	if err != nil {
+		return "", err
+	}
+	if owner {
+		return config.Config.Siwe.OwnerRole, nil
+	}
+	return models.RoleWallet, nil
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"pledge-backend/api/models/chain"
+	"pledge-backend/api/models/kucoin"
+	"pledge-backend/api/models/response"
+	"pledge-backend/config"
+	"pledge-backend/db"
+	"pledge-backend/log"
+	"pledge-backend/schedule/models"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// checkTimeout budget of a single dependency probe
+const checkTimeout = 2 * time.Second
+
+var startedAt = time.Now()
+
+type StatusService struct{}
+
+func NewStatus() *StatusService {
+	return &StatusService{}
+}
+
+// probe run check with the probe timeout and time it
+func probe(name string, check func(ctx context.Context) error) response.Dependency {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	start := time.Now()
+	err := check(ctx)
+	dep := response.Dependency{Name: name, Status: response.StatusUp, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		dep.Status, dep.Error = response.StatusDown, err.Error()
+	}
+	return dep
+}
+
+// Ready mysql and redis, the dependencies no request can be served without
+func (s *StatusService) Ready() (bool, response.Ready) {
+	deps := make([]response.Dependency, 2)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		deps[0] = probe("mysql", db.MysqlPing)
+	}()
+	go func() {
+		defer wg.Done()
+		deps[1] = probe("redis", db.RedisPing)
+	}()
+	wg.Wait()
+
+	res := response.Ready{Status: response.StatusOk, Dependencies: deps}
+	for _, dep := range deps {
+		if dep.Status != response.StatusUp {
+			res.Status = response.StatusDown
+		}
+	}
+	return res.Status == response.StatusOk, res
+}
+
+// Status readiness plus rpc nodes, scheduler runs and price feed. A down rpc node or a stale price
+// makes the service degraded, not unready
+func (s *StatusService) Status() response.Status {
+	res := response.Status{
+		Status:        response.StatusOk,
+		Version:       config.Config.Env.Version,
+		StartedAt:     startedAt.Unix(),
+		ConfigVersion: config.Current().Version,
+	}
+
+	var wg sync.WaitGroup
+	var ready response.Ready
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, ready = s.Ready()
+	}()
+	chainIds := []string{config.Config.TestNet.ChainId, config.Config.MainNet.ChainId}
+	res.Chains = make([]response.ChainStatus, len(chainIds))
+	for i, chainId := range chainIds {
+		wg.Add(1)
+		go func(i int, chainId string) {
+			defer wg.Done()
+			res.Chains[i] = s.chain(chainId)
+		}(i, chainId)
+	}
+	wg.Wait()
+
+	res.Dependencies = ready.Dependencies
+	if ready.Status != response.StatusOk {
+		res.Status = response.StatusDown
+	}
+
+	syncs, err := models.ChainSyncs()
+	if err != nil {
+		log.Logger.Sugar().Error("status chain sync err ", err)
+	}
+	for i := range res.Chains {
+		c := &res.Chains[i]
+		if synced, ok := syncs[c.ChainId]; ok {
+			c.SyncedBlock, c.SyncedAt = synced.Block, synced.SyncedAt
+			if c.HeadBlock > 0 {
+				c.LagBlocks = int64(c.HeadBlock) - int64(synced.Block)
+			}
+		}
+		if c.Rpc.Status != response.StatusUp {
+			res.Degrade()
+		}
+	}
+
+	res.Jobs = s.jobs()
+	res.PriceFeed = s.priceFeed()
+	for _, feed := range res.PriceFeed {
+		if feed.Stale {
+			res.Degrade()
+		}
+	}
+	return res
+}
+
+// chain probe the rpc node of chainId for its latest block
+func (s *StatusService) chain(chainId string) response.ChainStatus {
+	res := response.ChainStatus{ChainId: chainId}
+	res.Rpc = probe("rpc:"+chainId, func(ctx context.Context) error {
+		id, err := strconv.Atoi(chainId)
+		if err != nil {
+			return err
+		}
+		client, err := chain.Dial(ctx, id)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		res.HeadBlock, err = client.BlockNumber(ctx)
+		return err
+	})
+	return res
+}
+
+func (s *StatusService) jobs() []response.JobStatus {
+	runs, err := models.JobRuns()
+	if err != nil {
+		log.Logger.Sugar().Error("status job runs err ", err)
+	}
+	res := make([]response.JobStatus, 0, len(runs))
+	for _, run := range runs {
+		res = append(res, response.JobStatus{
+			Name:        run.Name,
+			Schedule:    run.Schedule,
+			Running:     run.Running,
+			LastRun:     run.LastRun,
+			NextRun:     run.NextRun,
+			LastSuccess: run.LastSuccess,
+			DurationMs:  run.DurationMs,
+			Attempts:    run.Attempts,
+			Aborted:     run.Aborted,
+			Error:       run.Error,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+func (s *StatusService) priceFeed() []response.PriceFeedStatus {
+	staleTimeout := kucoin.StaleTimeout()
+	symbols := kucoin.NewFeed(config.Config.Kucoin).Symbols()
+	sort.Strings(symbols)
+	res := make([]response.PriceFeedStatus, 0, len(symbols))
+	for _, symbol := range symbols {
+		feed := response.PriceFeedStatus{Symbol: symbol, AgeSeconds: -1, Stale: true}
+		if price, _, ok := kucoin.GetPrice(symbol); ok {
+			feed.Price = price.Price
+		}
+		if age, ok := kucoin.GetPriceAge(symbol); ok {
+			feed.AgeSeconds = int64(age.Seconds())
+			feed.Stale = age > staleTimeout
+		}
+		res = append(res, feed)
+	}
+	return res
+}
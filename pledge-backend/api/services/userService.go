@@ -1,34 +1,145 @@
 package services
 
 import (
+	"errors"
 	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models"
 	"pledge-backend/api/models/request"
 	"pledge-backend/api/models/response"
 	"pledge-backend/config"
-	"pledge-backend/db"
 	"pledge-backend/log"
 	"pledge-backend/utils"
 )
 
-type UserService struct{}
+type UserService struct {
+	audit *models.AuditLog
+}
 
 func NewUser() *UserService {
 	return &UserService{}
 }
 
-func (s *UserService) Login(req *request.Login, result *response.Login) int {
-	log.Logger.Sugar().Info("contractService", req)
-	if req.Name == "admin" && req.Password == "password" {
-		token, err := utils.CreateToken(req.Name)
-		if err != nil {
-			log.Logger.Error("CreateToken" + err.Error())
+// Audit record changes into entry, the audit log of the request (nil when it is not audited)
+func (s *UserService) Audit(entry *models.AuditLog) *UserService {
+	s.audit = entry
+	return s
+}
+
+func (s *UserService) Login(req *request.Login, ip string, userAgent string, result *response.Login) int {
+	s.audit.SetActor(req.Name)
+	code, admin := NewAdmin().Authenticate(req.Name, req.Password)
+	if code != statecode.CommonSuccess {
+		return code
+	}
+	session := models.NewSession()
+	refreshToken, err := session.Create(admin.Name, ip, userAgent)
+	if err != nil {
+		log.Logger.Error("CreateSession" + err.Error())
+		return statecode.CommonErrServerErr
+	}
+	s.audit.Record("session", session.SessionId, nil, nil)
+	return s.issue(utils.TokenClaims{Username: admin.Name, Role: admin.Role, SessionId: session.SessionId}, refreshToken, result)
+}
+
+// Refresh exchange a refresh token for a new access token and a new refresh token
+func (s *UserService) Refresh(req *request.Refresh, result *response.Login) int {
+	session := models.NewSession()
+	refreshToken, err := session.Rotate(req.RefreshToken)
+	if errors.Is(err, models.ErrSessionNotFound) || errors.Is(err, models.ErrRefreshToken) {
+		return statecode.RefreshErr
+	}
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
+	}
+	s.audit.SetActor(session.Username)
+	s.audit.Record("session", session.SessionId, nil, nil)
+	claims := utils.TokenClaims{Username: session.Username, SessionId: session.SessionId}
+	if session.Address != "" {
+		claims.Address, claims.ChainId = session.Address, session.ChainId
+		if claims.Role, err = NewSiwe().Role(session.ChainId, session.Address); err != nil {
+			log.Logger.Error(err.Error())
 			return statecode.CommonErrServerErr
 		}
-		result.TokenId = token
-		//save to redis
-		_ = db.RedisSet(req.Name, "login_ok", config.Config.Jwt.ExpireTime)
-		return statecode.CommonSuccess
 	} else {
-		return statecode.NameOrPasswordErr
+		admin, enabled := NewAdmin().Current(session.Username)
+		if !enabled {
+			_ = session.Revoke()
+			return statecode.RefreshErr
+		}
+		claims.Role = admin.Role
+	}
+	return s.issue(claims, refreshToken, result)
+}
+
+// Authorized whether the identity in claims still holds the role it was issued with:
+// the admin is enabled and its role unchanged, or the wallet's multi-sign ownership unchanged
+func (s *UserService) Authorized(claims *utils.TokenClaims) bool {
+	if claims.Address != "" {
+		role, err := NewSiwe().Role(claims.ChainId, claims.Address)
+		return err == nil && role == claims.Role
+	}
+	admin, enabled := NewAdmin().Current(claims.Username)
+	return enabled && admin.Role == claims.Role
+}
+
+// issue sign an access token for claims, permissions are read again on every issue
+func (s *UserService) issue(claims utils.TokenClaims, refreshToken string, result *response.Login) int {
+	claims.Permissions = NewRbac().Permissions(claims.Role)
+	token, err := utils.CreateToken(claims)
+	if err != nil {
+		log.Logger.Error("CreateToken" + err.Error())
+		return statecode.CommonErrServerErr
+	}
+	result.TokenId = token
+	result.RefreshToken = refreshToken
+	result.SessionId = claims.SessionId
+	result.ExpiresIn = config.Config.Jwt.ExpireTime
+	result.RefreshExpiresIn = config.Config.Jwt.RefreshExpireTime
+	return statecode.CommonSuccess
+}
+
+// Sessions live sessions of username, current marks the one making the request
+func (s *UserService) Sessions(username string, current string) (int, []response.Session) {
+	sessions, err := models.NewSession().List(username)
+	if err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr, nil
+	}
+	res := make([]response.Session, 0, len(sessions))
+	for _, session := range sessions {
+		res = append(res, response.Session{
+			SessionId:   session.SessionId,
+			Ip:          session.Ip,
+			UserAgent:   session.UserAgent,
+			CreatedAt:   session.CreatedAt,
+			RefreshedAt: session.RefreshedAt,
+			Current:     session.SessionId == current,
+		})
+	}
+	return statecode.CommonSuccess, res
+}
+
+// RevokeSession end one of username's own sessions
+func (s *UserService) RevokeSession(username string, sessionId string) int {
+	session := models.NewSession()
+	if err := session.Get(sessionId); err != nil || session.Username != username {
+		return statecode.SessionErr
+	}
+	if err := session.Revoke(); err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
+	}
+	s.audit.Record("session", sessionId, nil, nil)
+	return statecode.CommonSuccess
+}
+
+// LogoutAll end every session of username
+func (s *UserService) LogoutAll(username string) int {
+	if err := models.NewSession().RevokeAll(username); err != nil {
+		log.Logger.Error(err.Error())
+		return statecode.CommonErrServerErr
 	}
+	s.audit.Record("session", "*", nil, nil)
+	return statecode.CommonSuccess
 }
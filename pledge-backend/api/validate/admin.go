@@ -0,0 +1,122 @@
+package validate
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"io"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models"
+	"pledge-backend/api/models/request"
+	"pledge-backend/utils"
+	"regexp"
+)
+
+var adminNameRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{2,31}$`)
+
+type Admin struct{}
+
+func NewAdmin() *Admin {
+	return &Admin{}
+}
+
+// IsAdminName 字母开头，3-32位字母、数字或下划线
+func IsAdminName(name string) bool {
+	return adminNameRegexp.MatchString(name)
+}
+
+// IsAdminPassword 8-64位，bcrypt 只使用前72字节
+func IsAdminPassword(password string) bool {
+	return len(password) >= 8 && len(password) <= 64
+}
+
+func bindAdmin(c *gin.Context, req interface{}) int {
+	err := c.ShouldBindJSON(req)
+	if err == io.EOF {
+		return statecode.ParameterEmptyErr
+	} else if err != nil {
+		if _, ok := err.(validator.ValidationErrors); ok {
+			return statecode.ParameterEmptyErr
+		}
+		return statecode.CommonErrServerErr
+	}
+	return statecode.CommonSuccess
+}
+
+func (v *Admin) Create(c *gin.Context, req *request.CreateAdmin) int {
+	if code := bindAdmin(c, req); code != statecode.CommonSuccess {
+		return code
+	}
+	if !IsAdminName(req.Name) {
+		return statecode.NameFormatErr
+	}
+	if !IsAdminPassword(req.Password) {
+		return statecode.PasswordFormatErr
+	}
+	if req.Role == "" {
+		req.Role = models.RoleViewer
+	}
+	if !models.IsRole(req.Role) {
+		return statecode.RoleErr
+	}
+	return statecode.CommonSuccess
+}
+
+func (v *Admin) SetStatus(c *gin.Context, req *request.SetAdminStatus) int {
+	if code := bindAdmin(c, req); code != statecode.CommonSuccess {
+		return code
+	}
+	if *req.Status != models.AdminEnabled && *req.Status != models.AdminDisabled {
+		return statecode.CommonErrServerErr
+	}
+	return statecode.CommonSuccess
+}
+
+func (v *Admin) ChangePassword(c *gin.Context, req *request.ChangePassword) int {
+	if code := bindAdmin(c, req); code != statecode.CommonSuccess {
+		return code
+	}
+	if !IsAdminPassword(req.NewPassword) {
+		return statecode.PasswordFormatErr
+	}
+	return statecode.CommonSuccess
+}
+
+func (v *Admin) ResetPassword(c *gin.Context, req *request.ResetPassword) int {
+	if code := bindAdmin(c, req); code != statecode.CommonSuccess {
+		return code
+	}
+	if !IsAdminPassword(req.NewPassword) {
+		return statecode.PasswordFormatErr
+	}
+	return statecode.CommonSuccess
+}
+
+func (v *Admin) SetRole(c *gin.Context, req *request.SetAdminRole) int {
+	if code := bindAdmin(c, req); code != statecode.CommonSuccess {
+		return code
+	}
+	if !models.IsRole(req.Role) {
+		return statecode.RoleErr
+	}
+	return statecode.CommonSuccess
+}
+
+func (v *Admin) SetRolePermissions(c *gin.Context, req *request.SetRolePermissions) int {
+	if code := bindAdmin(c, req); code != statecode.CommonSuccess {
+		return code
+	}
+	if !models.IsRole(req.Role) || req.Role == models.RoleSuperAdmin {
+		return statecode.RoleErr
+	}
+	permissions := make([]string, 0, len(req.Permissions))
+	for _, p := range req.Permissions {
+		if !models.IsPermission(p) {
+			return statecode.PermissionNameErr
+		}
+		if !utils.IsContain(p, permissions) {
+			permissions = append(permissions, p)
+		}
+	}
+	req.Permissions = permissions
+	return statecode.CommonSuccess
+}
@@ -0,0 +1,30 @@
+package validate
+
+import (
+	"github.com/gin-gonic/gin"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models/request"
+	"strings"
+	"unicode/utf8"
+)
+
+type ApiKey struct{}
+
+func NewApiKey() *ApiKey {
+	return &ApiKey{}
+}
+
+func (v *ApiKey) Create(c *gin.Context, req *request.CreateApiKey) int {
+	if code := bindAdmin(c, req); code != statecode.CommonSuccess {
+		return code
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > 100 {
+		return statecode.ApiKeyNameErr
+	}
+	return statecode.CommonSuccess
+}
+
+func (v *ApiKey) Revoke(c *gin.Context, req *request.RevokeApiKey) int {
+	return bindAdmin(c, req)
+}
@@ -0,0 +1,32 @@
+package validate
+
+import (
+	"github.com/gin-gonic/gin"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models/request"
+)
+
+type Audit struct{}
+
+func NewAudit() *Audit {
+	return &Audit{}
+}
+
+func (v *Audit) Query(c *gin.Context, req *request.AuditQuery) int {
+	if err := c.ShouldBindQuery(req); err != nil {
+		return statecode.ParameterEmptyErr
+	}
+	if req.From < 0 || req.To < 0 || (req.To > 0 && req.From > req.To) {
+		return statecode.ParameterEmptyErr
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 10
+	}
+	if req.PageSize > MaxPageSize {
+		req.PageSize = MaxPageSize
+	}
+	return statecode.CommonSuccess
+}
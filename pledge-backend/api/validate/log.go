@@ -0,0 +1,25 @@
+package validate
+
+import (
+	"github.com/gin-gonic/gin"
+	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models/request"
+	"pledge-backend/config"
+	"pledge-backend/utils"
+)
+
+type Log struct{}
+
+func NewLog() *Log {
+	return &Log{}
+}
+
+func (v *Log) SetLogLevel(c *gin.Context, req *request.SetLogLevel) int {
+	if code := bindAdmin(c, req); code != statecode.CommonSuccess {
+		return code
+	}
+	if !utils.IsContain(req.Level, config.LogLevels) {
+		return statecode.LogLevelErr
+	}
+	return statecode.CommonSuccess
+}
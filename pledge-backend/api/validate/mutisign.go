@@ -6,6 +6,9 @@ import (
 	"io"
 	"pledge-backend/api/common/statecode"
 	"pledge-backend/api/models/request"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 type MutiSign struct{}
@@ -32,9 +35,34 @@ func (v *MutiSign) SetMultiSign(c *gin.Context, req *request.SetMultiSign) int {
 		return statecode.CommonErrServerErr
 	}
 
+	for _, address := range append([]string{req.SpAddress, req.JpAddress}, req.MultiSignAccount...) {
+		if !IsChecksumAddress(address) {
+			return statecode.AddressErr
+		}
+	}
+	if len(req.MultiSignAccount) == 0 {
+		return statecode.AddressErr
+	}
+	seen := map[string]bool{}
+	for _, account := range req.MultiSignAccount {
+		if seen[account] {
+			return statecode.AddressErr
+		}
+		seen[account] = true
+	}
+	// allow for clock skew between the client and the server
+	if req.EffectiveFrom != 0 && req.EffectiveFrom < time.Now().Add(-time.Minute).Unix() {
+		return statecode.EffectiveFromErr
+	}
+
 	return statecode.CommonSuccess
 }
 
+// IsChecksumAddress 0x address in EIP-55 mixed-case checksum form
+func IsChecksumAddress(address string) bool {
+	return common.IsHexAddress(address) && common.HexToAddress(address).Hex() == address
+}
+
 func (v *MutiSign) GetMultiSign(c *gin.Context, req *request.GetMultiSign) int {
 
 	err := c.ShouldBind(req)
@@ -5,9 +5,16 @@ import (
 	"github.com/go-playground/validator/v10"
 	"io"
 	"pledge-backend/api/common/statecode"
+	"pledge-backend/api/models"
 	"pledge-backend/api/models/request"
+	"regexp"
 )
 
+// MaxPageSize upper bound of pageSize on list endpoints
+const MaxPageSize = 100
+
+var uintRegexp = regexp.MustCompile(`^[0-9]{1,60}$`)
+
 type Search struct{}
 
 func NewSearch() *Search {
@@ -20,7 +27,11 @@ func (s *Search) Search(c *gin.Context, req *request.Search) int {
 	if err == io.EOF {
 		return statecode.ParameterEmptyErr
 	} else if err != nil {
-		errs := err.(validator.ValidationErrors)
+		// a wrong json type (sort not a list, fill ratio not a number) is no validation error
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			return statecode.ParameterEmptyErr
+		}
 		for _, e := range errs {
 			if e.Field() == "ChainID" && e.Tag() == "required" {
 				return statecode.ChainIdEmpty
@@ -33,5 +44,37 @@ func (s *Search) Search(c *gin.Context, req *request.Search) int {
 		return statecode.ChainIdErr
 	}
 
+	for _, v := range []string{req.InterestRateMin, req.InterestRateMax, req.MartgageRateMin, req.MartgageRateMax} {
+		if v != "" && !uintRegexp.MatchString(v) {
+			return statecode.SearchParamErr
+		}
+	}
+	for _, v := range []int64{req.SettleTimeFrom, req.SettleTimeTo, req.EndTimeFrom, req.EndTimeTo} {
+		if v < 0 {
+			return statecode.SearchParamErr
+		}
+	}
+	if (req.SettleTimeTo > 0 && req.SettleTimeFrom > req.SettleTimeTo) || (req.EndTimeTo > 0 && req.EndTimeFrom > req.EndTimeTo) {
+		return statecode.SearchParamErr
+	}
+	for _, v := range []*float64{req.FillRatioMin, req.FillRatioMax} {
+		if v != nil && (*v < 0 || *v > 1) {
+			return statecode.SearchParamErr
+		}
+	}
+	if _, ok := models.ParsePoolSorts(req.Sort); !ok {
+		return statecode.SearchParamErr
+	}
+
+	if req.PageSize <= 0 {
+		req.PageSize = 10
+	}
+	if req.PageSize > MaxPageSize {
+		req.PageSize = MaxPageSize
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+
 	return statecode.CommonSuccess
 }
@@ -34,3 +34,15 @@ func (v *User) Login(c *gin.Context, req *request.Login) int {
 
 	return statecode.CommonSuccess
 }
+
+func (v *User) Refresh(c *gin.Context, req *request.Refresh) int {
+	return bindAdmin(c, req)
+}
+
+func (v *User) RevokeSession(c *gin.Context, req *request.RevokeSession) int {
+	return bindAdmin(c, req)
+}
+
+func (v *User) SiweLogin(c *gin.Context, req *request.SiweLogin) int {
+	return bindAdmin(c, req)
+}
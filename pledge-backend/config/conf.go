@@ -1,28 +1,107 @@
 package config
 
+// Config the configuration loaded at startup, never replaced. Settings that can change live are read through Current()
 var Config *Conf
 
 type Conf struct {
-	Mysql        MysqlConfig
-	Redis        RedisConfig
-	TestNet      TestNetConfig
-	MainNet      MainNetConfig
-	Token        TokenConfig
-	Email        EmailConfig
-	DefaultAdmin DefaultAdminConfig
-	Threshold    ThresholdConfig
-	Jwt          JwtConfig
-	Env          EnvConfig
+	Mysql        MysqlConfig        `toml:"mysql"`
+	Redis        RedisConfig        `toml:"redis"`
+	TestNet      TestNetConfig      `toml:"testnet"`
+	MainNet      MainNetConfig      `toml:"mainnet"`
+	Token        TokenConfig        `toml:"token"`
+	Email        EmailConfig        `toml:"email"`
+	DefaultAdmin DefaultAdminConfig `toml:"defaultadmin"`
+	Threshold    ThresholdConfig    `toml:"threshold"`
+	Jwt          JwtConfig          `toml:"jwt"`
+	Siwe         SiweConfig         `toml:"siwe"`
+	RateLimit    RateLimitConfig    `toml:"ratelimit"`
+	Env          EnvConfig          `toml:"env"`
+	Kucoin       KucoinConfig       `toml:"kucoin"`
+	Schedule     ScheduleConfig     `toml:"schedule"`
+	Cors         CorsConfig         `toml:"cors"`
+	Tracing      TracingConfig      `toml:"tracing"`
+	Log          LogConfig          `toml:"log"`
+}
+
+// ScheduleConfig when each scheduler job runs
+type ScheduleConfig struct {
+	PoolInfo       JobConfig `toml:"pool_info"`
+	ContractPrice  JobConfig `toml:"contract_price"`
+	ContractSymbol JobConfig `toml:"contract_symbol"`
+	TokenLogo      JobConfig `toml:"token_logo"`
+	BalanceMonitor JobConfig `toml:"balance_monitor"`
+	PlgrPrice      JobConfig `toml:"plgr_price"`
+}
+
+// JobConfig schedule of one job. A job never overlaps itself, a run due while the previous one is still going waits for it
+type JobConfig struct {
+	Cron    string `toml:"cron"`    // standard 5 field cron expression in UTC, e.g. "*/5 * * * *", takes precedence over every
+	Every   int64  `toml:"every"`   // min, 0 uses env.task_duration
+	Timeout int64  `toml:"timeout"` // s, a run is cancelled after this long, 0 uses env.task_extend_duration (min)
+	Jitter  int64  `toml:"jitter"`  // s, random delay up to this before each run, so jobs due together spread out
+	Retries int    `toml:"retries"` // failed runs are retried this many times
+	Backoff int64  `toml:"backoff"` // s, wait before the first retry, doubled for each further one
+}
+
+type CorsConfig struct {
+	AllowOrigins []string `toml:"allow_origins"` // "*" allows every origin
+}
+
+// TracingConfig where spans go, sampling applies to new traces, a sampled parent is always followed
+type TracingConfig struct {
+	Exporter    string  `toml:"exporter"`     // none, stdout or otlp
+	Endpoint    string  `toml:"endpoint"`     // otlp/http traces url, e.g. http://localhost:4318/v1/traces
+	SampleRatio float64 `toml:"sample_ratio"` // 0 - 1
+}
+
+// LogConfig where logs go, how much of them and what they may not contain
+type LogConfig struct {
+	Level              string            `toml:"level"`               // debug, info, warn, error; changeable at runtime through the admin api
+	Encoding           string            `toml:"encoding"`            // json or console
+	Outputs            []string          `toml:"outputs"`             // stdout, stderr or file paths, files are rotated
+	MaxSize            int               `toml:"max_size"`            // MB, a file is rotated once it reaches this size
+	MaxBackups         int               `toml:"max_backups"`         // rotated files kept, 0 keeps all
+	MaxAge             int               `toml:"max_age"`             // days rotated files are kept, 0 keeps them forever
+	Compress           bool              `toml:"compress"`            // gzip rotated files
+	SamplingInitial    int               `toml:"sampling_initial"`    // per second and message, the first n entries are logged, 0 disables sampling
+	SamplingThereafter int               `toml:"sampling_thereafter"` // then every n-th one
+	Development        bool              `toml:"development"`         // stack traces from warn, dpanic panics
+	Packages           map[string]string `toml:"packages"`            // level per package path prefix, e.g. "pledge-backend/schedule" = "debug"
+	RedactKeys         []string          `toml:"redact_keys"`         // extra field names whose values are masked, on top of password, token, secret...
 }
 
 type EnvConfig struct {
-	Port               string `toml:"port"`
-	Version            string `toml:"version"`
-	Protocol           string `toml:"protocol"`
-	DomainName         string `toml:"domain_name"`
-	TaskDuration       int64  `toml:"task_duration"`
-	WssTimeoutDuration int64  `toml:"wss_timeout_duration"`
-	TaskExtendDuration int64  `toml:"task_extend_duration"`
+	Port               string   `toml:"port"`
+	Version            string   `toml:"version"`
+	Protocol           string   `toml:"protocol"`
+	DomainName         string   `toml:"domain_name"`
+	TaskDuration       int64    `toml:"task_duration"` // min, interval of a scheduler job without cron or every
+	WssTimeoutDuration int64    `toml:"wss_timeout_duration"`
+	TaskExtendDuration int64    `toml:"task_extend_duration"` // min, timeout of a scheduler job without its own
+	WssMaxConnections  int      `toml:"wss_max_connections"`
+	WssMaxConnPerIp    int      `toml:"wss_max_conn_per_ip"`
+	WssSendBufferSize  int      `toml:"wss_send_buffer_size"` // queued frames per client before it is evicted
+	ShutdownTimeout    int64    `toml:"shutdown_timeout"`     // s, on SIGTERM in-flight requests and the running job get this long to finish
+	TaskMetricsPort    string   `toml:"task_metrics_port"`    // scheduler /metrics listener, empty disables it; the api serves /metrics on port
+	TrustedProxies     []string `toml:"trusted_proxies"`      // ips or cidrs of the reverse proxies whose X-Forwarded-For is believed, empty uses the socket address
+}
+
+type KucoinConfig struct {
+	BaseUri          string               `toml:"base_uri"`
+	ApiKey           string               `toml:"api_key"`
+	ApiSecret        string               `toml:"api_secret"`
+	ApiPassphrase    string               `toml:"api_passphrase"`
+	MinBackoff       int64                `toml:"min_backoff"`        // s
+	MaxBackoff       int64                `toml:"max_backoff"`        // s
+	RestPollInterval int64                `toml:"rest_poll_interval"` // s
+	StaleTimeout     int64                `toml:"stale_timeout"`      // s
+	Markets          []KucoinMarketConfig `toml:"markets"`
+}
+
+type KucoinMarketConfig struct {
+	Symbol  string `toml:"symbol"`
+	Token   string `toml:"token"`
+	ChainId string `toml:"chain_id"`
 }
 
 type ThresholdConfig struct {
@@ -46,8 +125,29 @@ type DefaultAdminConfig struct {
 }
 
 type JwtConfig struct {
-	SecretKey  string `toml:"secret_key"`
-	ExpireTime int    `toml:"expire_time"` // duration, s
+	SecretKey         string `toml:"secret_key"`
+	ExpireTime        int    `toml:"expire_time"`         // access token lifetime, s
+	RefreshExpireTime int    `toml:"refresh_expire_time"` // refresh token (session) lifetime since last refresh, s
+}
+
+// SiweConfig Sign-In with Ethereum (EIP-4361)
+type SiweConfig struct {
+	Domain    string `toml:"domain"`     // host of the site asking for the signature, messages for other domains are rejected
+	NonceTTL  int    `toml:"nonce_ttl"`  // how long an issued nonce may be used, s
+	OwnerRole string `toml:"owner_role"` // role of addresses in the multi-sign owner list of the chain
+}
+
+type RateLimitConfig struct {
+	Enabled bool                            `toml:"enabled"`
+	Groups  map[string]RateLimitGroupConfig `toml:"groups"` // route group -> quota, groups not listed are not limited
+}
+
+// RateLimitGroupConfig token bucket quotas, rate is tokens refilled per second and burst the bucket size
+type RateLimitGroupConfig struct {
+	IpRate   float64 `toml:"ip_rate"`   // per client ip without api key
+	IpBurst  int     `toml:"ip_burst"`  // per client ip without api key
+	KeyRate  float64 `toml:"key_rate"`  // per api key
+	KeyBurst int     `toml:"key_burst"` // per api key
 }
 
 type TokenConfig struct {
@@ -66,19 +166,21 @@ type MysqlConfig struct {
 }
 
 type TestNetConfig struct {
-	ChainId              string `toml:"chain_id"`
-	NetUrl               string `toml:"net_url"`
-	PlgrAddress          string `toml:"plgr_address"`
-	PledgePoolToken      string `toml:"pledge_pool_token"`
-	BscPledgeOracleToken string `toml:"bsc_pledge_oracle_token"`
+	ChainId               string `toml:"chain_id"`
+	NetUrl                string `toml:"net_url"`
+	PlgrAddress           string `toml:"plgr_address"`
+	PledgePoolToken       string `toml:"pledge_pool_token"`
+	BscPledgeOracleToken  string `toml:"bsc_pledge_oracle_token"`
+	MultiSignatureAddress string `toml:"multi_signature_address"` // empty: read getMultiSignatureAddress() of the pledge pool
 }
 
 type MainNetConfig struct {
-	ChainId              string `toml:"chain_id"`
-	NetUrl               string `toml:"net_url"`
-	PlgrAddress          string `toml:"plgr_address"`
-	PledgePoolToken      string `toml:"pledge_pool_token"`
-	BscPledgeOracleToken string `toml:"bsc_pledge_oracle_token"`
+	ChainId               string `toml:"chain_id"`
+	NetUrl                string `toml:"net_url"`
+	PlgrAddress           string `toml:"plgr_address"`
+	PledgePoolToken       string `toml:"pledge_pool_token"`
+	BscPledgeOracleToken  string `toml:"bsc_pledge_oracle_token"`
+	MultiSignatureAddress string `toml:"multi_signature_address"` // empty: read getMultiSignatureAddress() of the pledge pool
 }
 
 type RedisConfig struct {
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix every toml key can be overridden by PLEDGE_<SECTION>_<KEY>, e.g. PLEDGE_MYSQL_PASSWORD for [mysql] password.
+// Nested keys join with "_" (PLEDGE_RATELIMIT_GROUPS_PUBLIC_IP_RATE, PLEDGE_KUCOIN_MARKETS_0_SYMBOL),
+// map entries and array elements must already exist in the file, string lists are comma separated.
+// PLEDGE_<...>_FILE reads the value from a file instead, for secrets mounted by docker or kubernetes.
+const EnvPrefix = "PLEDGE"
+
+type lookupEnv func(key string) (string, bool)
+
+// applyEnv override conf from the environment, returns every variable that could not be applied
+func applyEnv(conf *Conf, lookup lookupEnv) []string {
+	var problems []string
+	walkEnv(reflect.ValueOf(conf).Elem(), EnvPrefix, lookup, &problems)
+	return problems
+}
+
+func walkEnv(v reflect.Value, name string, lookup lookupEnv, problems *[]string) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			walkEnv(v.Field(i), name+"_"+envKey(t.Field(i)), lookup, problems)
+		}
+		return
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.Struct {
+			break
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			// map values are not addressable, override a copy and put it back
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			walkEnv(elem, name+"_"+strings.ToUpper(key.String()), lookup, problems)
+			v.SetMapIndex(key, elem)
+		}
+		return
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			for i := 0; i < v.Len(); i++ {
+				walkEnv(v.Index(i), name+"_"+strconv.Itoa(i), lookup, problems)
+			}
+			return
+		}
+	}
+
+	value, ok, err := envValue(name, lookup)
+	if err != nil {
+		*problems = append(*problems, err.Error())
+		return
+	}
+	if !ok {
+		return
+	}
+	if err = setValue(v, value); err != nil {
+		*problems = append(*problems, fmt.Sprintf("%s: %s", name, err.Error()))
+	}
+}
+
+// envValue NAME or the content of the file named by NAME_FILE, setting both is an error
+func envValue(name string, lookup lookupEnv) (string, bool, error) {
+	value, ok := lookup(name)
+	file, fromFile := lookup(name + "_FILE")
+	if !fromFile {
+		return value, ok, nil
+	}
+	if ok {
+		return "", false, fmt.Errorf("%s and %s_FILE are both set", name, name)
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", false, fmt.Errorf("%s_FILE: %s", name, err.Error())
+	}
+	return strings.TrimRight(string(content), "\r\n"), true, nil
+}
+
+func setValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		v.SetInt(n)
+	case reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a positive integer", value)
+		}
+		v.SetUint(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("cannot be set from the environment")
+		}
+		list := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("cannot be set from the environment")
+	}
+	return nil
+}
+
+// envKey upper cased toml key of a field, the field name when it has no toml tag
+func envKey(field reflect.StructField) string {
+	key := strings.Split(field.Tag.Get("toml"), ",")[0]
+	if key == "" {
+		key = field.Name
+	}
+	return strings.ToUpper(key)
+}
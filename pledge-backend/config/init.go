@@ -1,28 +1,76 @@
 package config
 
 import (
+	"fmt"
+	"os"
 	"path"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
 
+// ConfigEnv environment variable naming the config file when --config is not given
+const ConfigEnv = "PLEDGE_CONFIG"
+
+// Path the config file that was loaded
+var Path string
+
 func init() {
-	// 1. 获取配置文件路径
-	currentAbPath := getCurrentAbPathByCaller()
-	tomlFile, err := filepath.Abs(currentAbPath + "/configV21.toml")
-	//tomlFile, err := filepath.Abs(currentAbPath + "/configV22.toml")
+	// 1. 获取配置文件路径: --config, PLEDGE_CONFIG, 默认源码目录下的 configV21.toml
+	tomlFile, err := filepath.Abs(configPath(os.Args[1:]))
 	if err != nil {
 		panic("read toml file err: " + err.Error())
-		return
 	}
 
-	// 2. 解析 TOML 文件到 Config 结构体
-	if _, err := toml.DecodeFile(tomlFile, &Config); err != nil {
-		panic("read toml file err: " + err.Error())
-		return
+	// 2. 解析 TOML 文件，环境变量覆盖后校验，所有错误一次报告
+	conf, err := Load(tomlFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	Config, Path, loadedSum = conf, tomlFile, fileSum(tomlFile)
+	current.Store(&Snapshot{Conf: conf, Version: 1, LoadedAt: time.Now()})
+}
+
+// Load decode the toml file, apply PLEDGE_* environment overrides and validate the result
+func Load(tomlFile string) (*Conf, error) {
+	conf := &Conf{}
+	if _, err := toml.DecodeFile(tomlFile, conf); err != nil {
+		return nil, fmt.Errorf("read toml file %s err: %s", tomlFile, err.Error())
+	}
+	problems := applyEnv(conf, os.LookupEnv)
+	problems = append(problems, conf.Validate()...)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid config %s:\n  %s", tomlFile, strings.Join(problems, "\n  "))
+	}
+	return conf, nil
+}
+
+// configPath --config <file> / --config=<file> (also -config), then PLEDGE_CONFIG, then configV21.toml next to this file
+func configPath(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		if name == "config" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(name, "config=") {
+			return strings.TrimPrefix(name, "config=")
+		}
+	}
+	if file := os.Getenv(ConfigEnv); file != "" {
+		return file
 	}
+	return getCurrentAbPathByCaller() + "/configV21.toml"
+	//return getCurrentAbPathByCaller() + "/configV22.toml"
 }
 
 func getCurrentAbPathByCaller() string {
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// WatchInterval how often the config file is checked for changes
+const WatchInterval = 5 * time.Second
+
+// Snapshot an immutable, versioned view of the configuration.
+// Config keeps the settings loaded at startup, settings that are safe to change live
+// (see live) must be read through Current() so a reload reaches them.
+type Snapshot struct {
+	*Conf
+	Version  int64
+	LoadedAt time.Time
+}
+
+// Change a setting that differs between two snapshots, secrets are masked
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Key, c.Old, c.New)
+}
+
+// Logger what Watch reports through, satisfied by log.Logger.Sugar()
+type Logger interface {
+	Infof(template string, args ...interface{})
+	Warnf(template string, args ...interface{})
+	Errorf(template string, args ...interface{})
+}
+
+var current atomic.Value // *Snapshot
+var loadedSum string     // hash of the file loaded at startup
+
+var reloadMu sync.Mutex
+var subscribers []func(prev *Snapshot, cur *Snapshot)
+
+// Current the snapshot in effect
+func Current() *Snapshot {
+	return current.Load().(*Snapshot)
+}
+
+// Subscribe call fn after every reload that changed a live setting, fn runs on the watcher goroutine
+func Subscribe(fn func(prev *Snapshot, cur *Snapshot)) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	subscribers = append(subscribers, fn)
+}
+
+// live the settings a reload applies, everything else needs a restart
+func live(cur *Conf, next *Conf) *Conf {
+	merged := *cur
+	merged.Threshold = next.Threshold
+	merged.Email = next.Email
+	merged.Token = next.Token
+	merged.Schedule = next.Schedule
+	merged.Cors = next.Cors
+	merged.RateLimit = next.RateLimit
+	merged.Env.WssTimeoutDuration = next.Env.WssTimeoutDuration
+	return &merged
+}
+
+// Reload load and validate the config file again and swap in the live settings.
+// Returns the applied changes and the changed keys that only take effect after a restart,
+// the snapshot in effect is kept when the file is invalid.
+func Reload() (applied []Change, restart []string, err error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	next, err := Load(Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	prev := Current()
+	merged := live(prev.Conf, next)
+	applied = diff(prev.Conf, merged)
+	for _, change := range diff(merged, next) {
+		restart = append(restart, change.Key)
+	}
+	if len(applied) == 0 {
+		return nil, restart, nil
+	}
+
+	cur := &Snapshot{Conf: merged, Version: prev.Version + 1, LoadedAt: time.Now()}
+	current.Store(cur)
+	for _, fn := range subscribers {
+		fn(prev, cur)
+	}
+	return applied, restart, nil
+}
+
+// Watch reload when the config file changes or on SIGHUP, until ctx is cancelled
+func Watch(ctx context.Context, logger Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+
+	sum := loadedSum
+	for {
+		select {
+		case <-hup:
+			logger.Infof("SIGHUP, reloading %s", Path)
+		case <-ticker.C:
+			if next := fileSum(Path); next == sum {
+				continue
+			}
+		case <-ctx.Done():
+			return
+		}
+		sum = fileSum(Path)
+
+		applied, restart, err := Reload()
+		if err != nil {
+			logger.Errorf("config reload failed, keeping version %d: %s", Current().Version, err.Error())
+			continue
+		}
+		if len(restart) > 0 {
+			logger.Warnf("config changes ignored until restart: %s", strings.Join(restart, ", "))
+		}
+		if len(applied) == 0 {
+			continue
+		}
+		lines := make([]string, 0, len(applied))
+		for _, change := range applied {
+			lines = append(lines, change.String())
+		}
+		logger.Infof("config reloaded, version %d: %s", Current().Version, strings.Join(lines, "; "))
+	}
+}
+
+// fileSum content hash of the file, "" when it cannot be read (e.g. while an editor replaces it)
+func fileSum(file string) string {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
+
+// diff keys whose value differs between a and b, sorted
+func diff(a *Conf, b *Conf) []Change {
+	before, after := flatten(a), flatten(b)
+	keys := map[string]bool{}
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+	var changes []Change
+	for key := range keys {
+		if before[key] == after[key] {
+			continue
+		}
+		change := Change{Key: key, Old: before[key], New: after[key]}
+		if isSecret(key) {
+			change.Old, change.New = "***", "***"
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+var secretKeys = []string{"password", "pwd", "secret", "api_key", "passphrase"}
+
+func isSecret(key string) bool {
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// flatten dotted toml key -> value of every setting, read only
+func flatten(conf *Conf) map[string]string {
+	values := map[string]string{}
+	var walk func(v reflect.Value, key string)
+	walk = func(v reflect.Value, key string) {
+		switch v.Kind() {
+		case reflect.Struct:
+			t := v.Type()
+			for i := 0; i < t.NumField(); i++ {
+				name := strings.ToLower(envKey(t.Field(i)))
+				if key != "" {
+					name = key + "." + name
+				}
+				walk(v.Field(i), name)
+			}
+		case reflect.Map:
+			for _, k := range v.MapKeys() {
+				walk(v.MapIndex(k), key+"."+k.String())
+			}
+		case reflect.Slice:
+			if v.Type().Elem().Kind() == reflect.Struct {
+				for i := 0; i < v.Len(); i++ {
+					walk(v.Index(i), key+"["+strconv.Itoa(i)+"]")
+				}
+				return
+			}
+			items := make([]string, 0, v.Len())
+			for i := 0; i < v.Len(); i++ {
+				items = append(items, fmt.Sprint(v.Index(i).Interface()))
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			values[key] = fmt.Sprint(v.Interface())
+		}
+	}
+	walk(reflect.ValueOf(conf).Elem(), "")
+	return values
+}
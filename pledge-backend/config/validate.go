@@ -0,0 +1,290 @@
+package config
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/robfig/cron/v3"
+)
+
+// problems settings that are missing or malformed, collected so they can be reported at once
+type problems []string
+
+func (p *problems) add(key string, format string, args ...interface{}) {
+	*p = append(*p, key+": "+fmt.Sprintf(format, args...))
+}
+
+func (p *problems) required(key string, value string) bool {
+	if value == "" {
+		p.add(key, "is required")
+		return false
+	}
+	return true
+}
+
+// LogLevels levels log.level and the admin api accept
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// logLevel one of LogLevels, empty means info
+func (p *problems) logLevel(key string, value string) {
+	if value == "" {
+		return
+	}
+	for _, level := range LogLevels {
+		if value == level {
+			return
+		}
+	}
+	p.add(key, "%q is not one of %s", value, strings.Join(LogLevels, ", "))
+}
+
+func (p *problems) port(key string, value string) {
+	if !p.required(key, value) {
+		return
+	}
+	if n, err := strconv.Atoi(value); err != nil || n <= 0 || n > 65535 {
+		p.add(key, "%q is not a port", value)
+	}
+}
+
+func (p *problems) positive(key string, value int64) {
+	if value <= 0 {
+		p.add(key, "must be greater than 0")
+	}
+}
+
+func (p *problems) notNegative(key string, value int64) {
+	if value < 0 {
+		p.add(key, "must not be negative")
+	}
+}
+
+func (p *problems) url(key string, value string, schemes ...string) {
+	if !p.required(key, value) {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		p.add(key, "%q is not a url", value)
+		return
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return
+		}
+	}
+	p.add(key, "scheme must be one of %v", schemes)
+}
+
+func (p *problems) address(key string, value string) {
+	if !p.required(key, value) {
+		return
+	}
+	if !common.IsHexAddress(value) {
+		p.add(key, "%q is not an address", value)
+	}
+}
+
+// Validate every setting the api, the scheduler and the bootstrap rely on
+func (c *Conf) Validate() []string {
+	p := &problems{}
+
+	p.required("mysql.address", c.Mysql.Address)
+	p.port("mysql.port", c.Mysql.Port)
+	p.required("mysql.db_name", c.Mysql.DbName)
+	p.required("mysql.user_name", c.Mysql.UserName)
+	p.notNegative("mysql.max_open_conns", int64(c.Mysql.MaxOpenConns))
+	p.notNegative("mysql.max_idle_conns", int64(c.Mysql.MaxIdleConns))
+	p.notNegative("mysql.max_life_time", int64(c.Mysql.MaxLifeTime))
+
+	p.required("redis.address", c.Redis.Address)
+	p.port("redis.port", c.Redis.Port)
+	p.notNegative("redis.db", int64(c.Redis.Db))
+	p.notNegative("redis.max_idle", int64(c.Redis.MaxIdle))
+	p.notNegative("redis.max_active", int64(c.Redis.MaxActive))
+	p.notNegative("redis.idle_timeout", int64(c.Redis.IdleTimeout))
+
+	for _, net := range []struct {
+		section                                                    string
+		chainId, netUrl, plgr, pool, oracle, multiSignatureAddress string
+	}{
+		{"testnet", c.TestNet.ChainId, c.TestNet.NetUrl, c.TestNet.PlgrAddress, c.TestNet.PledgePoolToken, c.TestNet.BscPledgeOracleToken, c.TestNet.MultiSignatureAddress},
+		{"mainnet", c.MainNet.ChainId, c.MainNet.NetUrl, c.MainNet.PlgrAddress, c.MainNet.PledgePoolToken, c.MainNet.BscPledgeOracleToken, c.MainNet.MultiSignatureAddress},
+	} {
+		if p.required(net.section+".chain_id", net.chainId) {
+			if n, err := strconv.Atoi(net.chainId); err != nil || n <= 0 {
+				p.add(net.section+".chain_id", "%q is not a chain id", net.chainId)
+			}
+		}
+		p.url(net.section+".net_url", net.netUrl, "http", "https", "ws", "wss")
+		p.address(net.section+".plgr_address", net.plgr)
+		p.address(net.section+".pledge_pool_token", net.pool)
+		p.address(net.section+".bsc_pledge_oracle_token", net.oracle)
+		if net.multiSignatureAddress != "" {
+			p.address(net.section+".multi_signature_address", net.multiSignatureAddress)
+		}
+	}
+	if c.TestNet.ChainId != "" && c.TestNet.ChainId == c.MainNet.ChainId {
+		p.add("mainnet.chain_id", "must differ from testnet.chain_id")
+	}
+
+	p.url("token.logo_url", c.Token.LogoUrl, "http", "https")
+
+	p.required("email.host", c.Email.Host)
+	p.port("email.port", c.Email.Port)
+	p.required("email.from", c.Email.From)
+	if len(c.Email.To) == 0 {
+		p.add("email.to", "is required")
+	}
+
+	if p.required("threshold.pledge_pool_token_threshold_bnb", c.Threshold.PledgePoolTokenThresholdBnb) {
+		if n, ok := new(big.Int).SetString(c.Threshold.PledgePoolTokenThresholdBnb, 10); !ok || n.Sign() < 0 {
+			p.add("threshold.pledge_pool_token_threshold_bnb", "%q is not an amount in wei", c.Threshold.PledgePoolTokenThresholdBnb)
+		}
+	}
+
+	if p.required("jwt.secret_key", c.Jwt.SecretKey) && len(c.Jwt.SecretKey) < 16 {
+		p.add("jwt.secret_key", "must be at least 16 characters")
+	}
+	p.positive("jwt.expire_time", int64(c.Jwt.ExpireTime))
+	if c.Jwt.RefreshExpireTime <= c.Jwt.ExpireTime {
+		p.add("jwt.refresh_expire_time", "must be greater than jwt.expire_time")
+	}
+
+	p.required("siwe.domain", c.Siwe.Domain)
+	p.positive("siwe.nonce_ttl", int64(c.Siwe.NonceTTL))
+	p.required("siwe.owner_role", c.Siwe.OwnerRole)
+
+	groups := make([]string, 0, len(c.RateLimit.Groups))
+	for group := range c.RateLimit.Groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		quota := c.RateLimit.Groups[group]
+		key := "ratelimit.groups." + group
+		if quota.IpRate <= 0 || quota.KeyRate <= 0 {
+			p.add(key, "ip_rate and key_rate must be greater than 0")
+		}
+		if quota.IpBurst < 1 || quota.KeyBurst < 1 {
+			p.add(key, "ip_burst and key_burst must be at least 1")
+		}
+	}
+
+	p.port("env.port", c.Env.Port)
+	p.required("env.version", c.Env.Version)
+	p.required("env.domain_name", c.Env.DomainName)
+	if c.Env.Protocol != "http" && c.Env.Protocol != "https" {
+		p.add("env.protocol", "must be http or https")
+	}
+	p.positive("env.wss_timeout_duration", c.Env.WssTimeoutDuration)
+	p.positive("env.shutdown_timeout", c.Env.ShutdownTimeout)
+	if c.Env.TaskMetricsPort != "" {
+		p.port("env.task_metrics_port", c.Env.TaskMetricsPort)
+		if c.Env.TaskMetricsPort == c.Env.Port {
+			p.add("env.task_metrics_port", "must differ from env.port")
+		}
+	}
+	for i, proxy := range c.Env.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				p.add(fmt.Sprintf("env.trusted_proxies[%d]", i), "%q is not an ip or cidr", proxy)
+			}
+		}
+	}
+	// 0 keeps the built-in default
+	p.notNegative("env.wss_max_connections", int64(c.Env.WssMaxConnections))
+	p.notNegative("env.wss_max_conn_per_ip", int64(c.Env.WssMaxConnPerIp))
+	p.notNegative("env.wss_send_buffer_size", int64(c.Env.WssSendBufferSize))
+
+	p.url("kucoin.base_uri", c.Kucoin.BaseUri, "http", "https")
+	// 0 keeps the built-in default
+	p.notNegative("kucoin.min_backoff", c.Kucoin.MinBackoff)
+	p.notNegative("kucoin.max_backoff", c.Kucoin.MaxBackoff)
+	p.notNegative("kucoin.rest_poll_interval", c.Kucoin.RestPollInterval)
+	p.notNegative("kucoin.stale_timeout", c.Kucoin.StaleTimeout)
+	for i, market := range c.Kucoin.Markets {
+		key := fmt.Sprintf("kucoin.markets[%d]", i)
+		p.required(key+".symbol", market.Symbol)
+		p.address(key+".token", market.Token)
+		p.required(key+".chain_id", market.ChainId)
+	}
+
+	switch c.Tracing.Exporter {
+	case "", "none", "stdout":
+	case "otlp":
+		p.url("tracing.endpoint", c.Tracing.Endpoint, "http", "https")
+	default:
+		p.add("tracing.exporter", "%q is not none, stdout or otlp", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		p.add("tracing.sample_ratio", "must be between 0 and 1")
+	}
+
+	p.logLevel("log.level", c.Log.Level)
+	if c.Log.Encoding != "" && c.Log.Encoding != "json" && c.Log.Encoding != "console" {
+		p.add("log.encoding", "%q is not json or console", c.Log.Encoding)
+	}
+	for i, output := range c.Log.Outputs {
+		p.required(fmt.Sprintf("log.outputs[%d]", i), output)
+	}
+	p.notNegative("log.max_size", int64(c.Log.MaxSize))
+	p.notNegative("log.max_backups", int64(c.Log.MaxBackups))
+	p.notNegative("log.max_age", int64(c.Log.MaxAge))
+	p.notNegative("log.sampling_initial", int64(c.Log.SamplingInitial))
+	p.notNegative("log.sampling_thereafter", int64(c.Log.SamplingThereafter))
+	pkgs := make([]string, 0, len(c.Log.Packages))
+	for pkg := range c.Log.Packages {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		p.required("log.packages", pkg)
+		p.logLevel("log.packages."+pkg, c.Log.Packages[pkg])
+	}
+	for i, key := range c.Log.RedactKeys {
+		p.required(fmt.Sprintf("log.redact_keys[%d]", i), key)
+	}
+
+	p.positive("env.task_duration", c.Env.TaskDuration)
+	p.positive("env.task_extend_duration", c.Env.TaskExtendDuration)
+	for _, job := range []struct {
+		key  string
+		conf JobConfig
+	}{
+		{"schedule.pool_info", c.Schedule.PoolInfo},
+		{"schedule.contract_price", c.Schedule.ContractPrice},
+		{"schedule.contract_symbol", c.Schedule.ContractSymbol},
+		{"schedule.token_logo", c.Schedule.TokenLogo},
+		{"schedule.balance_monitor", c.Schedule.BalanceMonitor},
+		{"schedule.plgr_price", c.Schedule.PlgrPrice},
+	} {
+		if job.conf.Cron != "" {
+			if _, err := cron.ParseStandard(job.conf.Cron); err != nil {
+				p.add(job.key+".cron", "%s", err.Error())
+			}
+		}
+		p.notNegative(job.key+".every", job.conf.Every)
+		p.notNegative(job.key+".timeout", job.conf.Timeout)
+		p.notNegative(job.key+".jitter", job.conf.Jitter)
+		p.notNegative(job.key+".retries", int64(job.conf.Retries))
+		p.notNegative(job.key+".backoff", job.conf.Backoff)
+	}
+
+	if len(c.Cors.AllowOrigins) == 0 {
+		p.add("cors.allow_origins", "is required, use [\"*\"] to allow every origin")
+	}
+	for i, origin := range c.Cors.AllowOrigins {
+		if origin != "*" {
+			p.url(fmt.Sprintf("cors.allow_origins[%d]", i), origin, "http", "https")
+		}
+	}
+
+	return *p
+}
@@ -7,3 +7,15 @@ import (
 
 var Mysql *gorm.DB
 var RedisConn *redis.Pool
+
+// Close release the mysql and redis connections, called last on shutdown
+func Close() {
+	if Mysql != nil {
+		if sqlDB, err := Mysql.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	}
+	if RedisConn != nil {
+		_ = RedisConn.Close()
+	}
+}
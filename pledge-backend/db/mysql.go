@@ -1,11 +1,18 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"pledge-backend/config"
 	"pledge-backend/log"
+	"pledge-backend/tracing"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
@@ -52,6 +59,14 @@ func InitMysql() {
 	_ = db.Callback().Row().After("gorm:row").Register("after_row", After)
 	_ = db.Callback().Raw().After("gorm:raw").Register("after_raw", After)
 
+	// 在每个操作前开启 span，由 After 结束
+	_ = db.Callback().Create().Before("gorm:create").Register("tracing:before_create", Before)
+	_ = db.Callback().Query().Before("gorm:query").Register("tracing:before_query", Before)
+	_ = db.Callback().Delete().Before("gorm:delete").Register("tracing:before_delete", Before)
+	_ = db.Callback().Update().Before("gorm:update").Register("tracing:before_update", Before)
+	_ = db.Callback().Row().Before("gorm:row").Register("tracing:before_row", Before)
+	_ = db.Callback().Raw().Before("gorm:raw").Register("tracing:before_raw", Before)
+
 	// 自动迁移数据表（已注释，需手动启用）
 	// db.AutoMigrate(&TestTable{})
 
@@ -70,11 +85,46 @@ func InitMysql() {
 	Mysql = db
 }
 
+// spanKey 存放当前操作 span 的 InstanceSet 键
+const spanKey = "tracing:span"
+
+// Before GORM回调函数，在所有数据库操作前执行
+// 语句的 ctx 带有请求或任务的 span 时开启一个子 span，用 Mysql.WithContext(ctx) 传入
+func Before(db *gorm.DB) {
+	_, span := tracing.StartChild(db.Statement.Context, "mysql "+db.Statement.Table, semconv.DBSystemMySQL)
+	if span.IsRecording() {
+		db.InstanceSet(spanKey, span)
+	}
+}
+
 // After GORM回调函数，在所有数据库操作后执行
-// 当前实现为解释SQL语句（可用于调试，实际已注释日志输出）
+// 结束 Before 开启的 span，解释SQL语句（可用于调试，实际已注释日志输出）
 func After(db *gorm.DB) {
 	db.Dialector.Explain(db.Statement.SQL.String(), db.Statement.Vars...)
+	if value, ok := db.InstanceGet(spanKey); ok {
+		span := value.(trace.Span)
+		// 只记录带 ? 占位符的语句，参数可能是用户数据，不写进 trace
+		span.SetAttributes(
+			semconv.DBStatementKey.String(db.Statement.SQL.String()),
+			semconv.DBSQLTableKey.String(db.Statement.Table),
+			attribute.Int64("db.rows_affected", db.RowsAffected),
+		)
+		err := db.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		tracing.End(span, err)
+	}
 	// 如需记录SQL日志，可取消下面注释
 	// sql := db.Dialector.Explain(db.Statement.SQL.String(), db.Statement.Vars...)
 	// log.Logger.Info(sql)
 }
+
+// MysqlPing 检查MySQL连接，ctx 控制超时
+func MysqlPing(ctx context.Context) error {
+	sqlDB, err := Mysql.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
@@ -1,13 +1,20 @@
 package db
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/gomodule/redigo/redis"
 	"pledge-backend/config"
 	"pledge-backend/log"
+	"pledge-backend/tracing"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
 )
 
 // InitRedis 初始化Redis
@@ -45,6 +52,43 @@ func InitRedis() *redis.Pool {
 	return RedisConn
 }
 
+// tracedConn 连接上的每条命令是 ctx 中 span 的一个子 span
+type tracedConn struct {
+	redis.Conn
+	ctx context.Context
+}
+
+func (c tracedConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	_, span := tracing.StartChild(c.ctx, "redis "+strings.ToUpper(commandName), semconv.DBSystemRedis)
+	reply, err := c.Conn.Do(commandName, args...)
+	tracing.End(span, ignoreNil(err))
+	return reply, err
+}
+
+func (c tracedConn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	_, span := tracing.StartChild(c.ctx, "redis "+strings.ToUpper(commandName), semconv.DBSystemRedis)
+	reply, err := redis.DoWithTimeout(c.Conn, timeout, commandName, args...)
+	tracing.End(span, ignoreNil(err))
+	return reply, err
+}
+
+// ignoreNil 不存在的key不算失败
+func ignoreNil(err error) error {
+	if errors.Is(err, redis.ErrNil) {
+		return nil
+	}
+	return err
+}
+
+// redisConn 从连接池取一个连接，ctx 控制等待时长并携带 span
+func redisConn(ctx context.Context) (redis.Conn, error) {
+	conn, err := RedisConn.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tracedConn{Conn: conn, ctx: ctx}, nil
+}
+
 // RedisSet 设置key、value、time
 func RedisSet(key string, data interface{}, aliveSeconds int) error {
 	conn := RedisConn.Get()
@@ -155,6 +199,23 @@ func RedisDelete(key string) (bool, error) {
 	return redis.Bool(conn.Do("del", key))
 }
 
+// RedisPing 检查Redis连接，ctx 控制超时
+func RedisPing(ctx context.Context) error {
+	conn, err := redisConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	timeout := time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	_, err = redis.DoWithTimeout(conn, timeout, "ping")
+	return err
+}
+
 // RedisFlushDB 清空当前DB
 func RedisFlushDB() error {
 	conn := RedisConn.Get()
@@ -168,6 +229,40 @@ func RedisFlushDB() error {
 	return nil
 }
 
+// RedisDeletePrefix 删除以 prefix 开头的全部key，用 SCAN 分批遍历，不阻塞 Redis，返回删除数量
+func RedisDeletePrefix(prefix string) (int, error) {
+	conn := RedisConn.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+	deleted := 0
+	cursor := "0"
+	for {
+		reply, err := redis.Values(conn.Do("scan", cursor, "match", prefix+"*", "count", 500))
+		if err != nil {
+			return deleted, err
+		}
+		var keys []string
+		if _, err = redis.Scan(reply, &cursor, &keys); err != nil {
+			return deleted, err
+		}
+		if len(keys) > 0 {
+			args := make([]interface{}, 0, len(keys))
+			for _, key := range keys {
+				args = append(args, key)
+			}
+			n, err := redis.Int(conn.Do("del", args...))
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+		}
+		if cursor == "0" {
+			return deleted, nil
+		}
+	}
+}
+
 // RedisGetHashOne 获取Heah其中一个值
 func RedisGetHashOne(key, name string) (interface{}, error) {
 	conn := RedisConn.Get()
@@ -291,6 +386,92 @@ func RedisSmembers(k string) ([]string, error) {
 	return reply, err
 }
 
+// RedisSRem 删除集合元素
+func RedisSRem(k string, v ...string) error {
+	conn := RedisConn.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+	args := []interface{}{k}
+	for _, m := range v {
+		args = append(args, m)
+	}
+	_, err := conn.Do("srem", args...)
+	return err
+}
+
+// RedisExpire 设置Key过期时间
+func RedisExpire(key string, aliveSeconds int) error {
+	conn := RedisConn.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+	_, err := conn.Do("expire", key, aliveSeconds)
+	return err
+}
+
+var compareAndSetScript = redis.NewScript(1, `
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	redis.call("set", KEYS[1], ARGV[2], "EX", ARGV[3])
+	return 1
+end
+return 0`)
+
+// RedisCompareAndSet 仅当Key的当前值等于old时写入value，返回是否写入
+func RedisCompareAndSet(key string, old string, value string, aliveSeconds int) (bool, error) {
+	conn := RedisConn.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+	return redis.Bool(compareAndSetScript.Do(conn, key, old, value, aliveSeconds))
+}
+
+var tokenBucketScript = redis.NewScript(1, `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call("hmget", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("hmset", KEYS[1], "tokens", tostring(tokens), "ts", tostring(now))
+redis.call("pexpire", KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}`)
+
+// RedisTokenBucket 令牌桶限流：每秒补充rate个令牌、最多burst个，取一个令牌，返回是否允许及剩余令牌数
+func RedisTokenBucket(ctx context.Context, key string, rate float64, burst int) (bool, float64, error) {
+	conn, err := redisConn(ctx)
+	if err != nil {
+		return false, 0, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	reply, err := redis.Values(tokenBucketScript.Do(conn, key, rate, burst, time.Now().UnixMilli()))
+	if err != nil {
+		return false, 0, err
+	}
+	var allowed int
+	var tokens string
+	if _, err = redis.Scan(reply, &allowed, &tokens); err != nil {
+		return false, 0, err
+	}
+	remaining, err := strconv.ParseFloat(tokens, 64)
+	if err != nil {
+		return false, 0, err
+	}
+	return allowed == 1, remaining, nil
+}
+
 type RedisEncryptionTask struct {
 	RecordOrderFlowId int32  `json:"recordOrderFlow"` //密码转账表ID
 	Encryption        string `json:"encryption"`      //密码串
@@ -346,3 +527,64 @@ func RedisDelList(setName string) error {
 	_, err := conn.Do("del", setName)
 	return err
 }
+
+// RedisPublish 发布消息到频道
+func RedisPublish(channel string, data interface{}) error {
+	conn := RedisConn.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+	value, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Do("publish", channel, value)
+	return err
+}
+
+// RedisSubscribe 订阅频道，阻塞直到连接出错或 ctx 取消
+func RedisSubscribe(ctx context.Context, handler func(channel string, data []byte), channels ...string) error {
+	conn := RedisConn.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+	psc := redis.PubSubConn{Conn: conn}
+	args := make([]interface{}, 0, len(channels))
+	for _, c := range channels {
+		args = append(args, c)
+	}
+	if err := psc.Subscribe(args...); err != nil {
+		return err
+	}
+	// unsubscribing makes Receive return, redigo allows it next to the reader
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-ctx.Done():
+			_ = psc.Unsubscribe()
+		case <-done:
+		}
+	}()
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			handler(v.Channel, v.Data)
+		case redis.Subscription:
+			if v.Count == 0 {
+				return ctx.Err()
+			}
+		case error:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return v
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package log
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// ContextKey gin context key of the request logger, so a *gin.Context works with FromContext too
+const ContextKey = "logger"
+
+type loggerKey struct{}
+
+// WithContext ctx carrying logger, see FromContext
+func WithContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext the logger of the request ctx belongs to, with its request id, route and ip fields.
+// Logger when ctx carries none, e.g. in the scheduler
+func FromContext(ctx context.Context) *zap.Logger {
+	if ctx == nil {
+		return Logger
+	}
+	if logger, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	if logger, ok := ctx.Value(ContextKey).(*zap.Logger); ok {
+		return logger
+	}
+	return Logger
+}
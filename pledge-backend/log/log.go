@@ -1,26 +1,34 @@
 package log
 
 import (
+	"fmt"
+	"os"
+	"pledge-backend/config"
+	"time"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
-	"os"
-	"path"
-	"runtime"
 )
 
 var Logger *zap.Logger
 
+// Level of every package without an override in [log.packages], changeable at runtime, see SetLevel
+var Level = zap.NewAtomicLevel()
+
 func init() {
+	logger, err := build(config.Config.Log)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "init logger err: "+err.Error())
+		os.Exit(1)
+	}
+	Logger = logger
+}
 
-	//zap 不支持文件归档，如果要支持文件按大小或者时间归档，需要使用lumberjack，lumberjack也是zap官方推荐的。
-	// https://github.com/uber-go/zap/blob/master/FAQ.md
-	hook := lumberjack.Logger{
-		Filename:   getCurrentAbPathByCaller() + "/logs/log.log", // 日志文件路径
-		MaxSize:    50,                                           // 每个日志文件保存的最大尺寸 单位：M
-		MaxBackups: 20,                                           // 日志文件最多保存多少个备份
-		MaxAge:     7,                                            // 文件最多保存多少天
-		Compress:   true,                                         // 是否压缩
+// build the logger described by [log]
+func build(conf config.LogConfig) (*zap.Logger, error) {
+	if err := Level.UnmarshalText([]byte(conf.Level)); err != nil {
+		return nil, err
 	}
 
 	encoderConfig := zapcore.EncoderConfig{
@@ -37,33 +45,72 @@ func init() {
 		EncodeCaller:   zapcore.FullCallerEncoder,      // 全路径编码器
 		EncodeName:     zapcore.FullNameEncoder,
 	}
+	var encoder zapcore.Encoder
+	if conf.Encoding == "console" {
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	} else {
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
 
-	// 设置日志级别
-	atomicLevel := zap.NewAtomicLevel()
-	atomicLevel.SetLevel(zap.InfoLevel)
+	packages, err := newPackageLevels(conf.Packages)
+	if err != nil {
+		return nil, err
+	}
+	// the inner core writes everything, levels are decided by packageCore once the caller is known
+	var core zapcore.Core = &packageCore{
+		Core:     zapcore.NewCore(encoder, outputs(conf), zapcore.DebugLevel),
+		level:    Level,
+		packages: packages,
+	}
+	if conf.SamplingInitial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, conf.SamplingInitial, conf.SamplingThereafter)
+	}
 
-	core := zapcore.NewCore(
-		zapcore.NewJSONEncoder(encoderConfig),                                           // 编码器配置
-		zapcore.NewMultiWriteSyncer(zapcore.AddSync(os.Stdout), zapcore.AddSync(&hook)), // 打印到控制台和文件
-		atomicLevel, // 日志级别
-	)
+	options := []zap.Option{
+		zap.AddCaller(), // 开启文件及行号
+		zap.Fields(zap.String("serviceName", "pledge")),
+	}
+	if conf.Development {
+		// 开发模式，warn 起带堆栈，DPanic 直接 panic
+		options = append(options, zap.Development(), zap.AddStacktrace(zapcore.WarnLevel))
+	}
+	return zap.New(core, options...), nil
+}
 
-	// 开启开发模式，堆栈跟踪
-	caller := zap.AddCaller()
-	// 开启文件及行号
-	development := zap.Development()
-	// 设置初始化字段
-	filed := zap.Fields(zap.String("serviceName", "pledge"))
-	// 构造日志
-	Logger = zap.New(core, caller, development, filed)
+// outputs stdout, stderr or a file rotated by lumberjack for every entry of [log] outputs, stdout when there is none.
+// zap 不支持文件归档，按大小或时间归档使用官方推荐的 lumberjack: https://github.com/uber-go/zap/blob/master/FAQ.md
+func outputs(conf config.LogConfig) zapcore.WriteSyncer {
+	if len(conf.Outputs) == 0 {
+		return zapcore.Lock(os.Stdout)
+	}
+	syncers := make([]zapcore.WriteSyncer, 0, len(conf.Outputs))
+	for _, output := range conf.Outputs {
+		switch output {
+		case "stdout":
+			syncers = append(syncers, zapcore.Lock(os.Stdout))
+		case "stderr":
+			syncers = append(syncers, zapcore.Lock(os.Stderr))
+		default:
+			syncers = append(syncers, zapcore.AddSync(&lumberjack.Logger{
+				Filename:   output,          // 日志文件路径，相对路径基于工作目录
+				MaxSize:    conf.MaxSize,    // 每个日志文件保存的最大尺寸 单位：M
+				MaxBackups: conf.MaxBackups, // 日志文件最多保存多少个备份
+				MaxAge:     conf.MaxAge,     // 文件最多保存多少天
+				Compress:   conf.Compress,   // 是否压缩
+			}))
+		}
+	}
+	return zapcore.NewMultiWriteSyncer(syncers...)
 }
 
-// 获取当前执行文件绝对路径（go run）
-func getCurrentAbPathByCaller() string {
-	var abPath string
-	_, filename, _, ok := runtime.Caller(0)
-	if ok {
-		abPath = path.Dir(filename)
+// SetLevel change the default level at runtime, packages with an override keep theirs
+func SetLevel(level string) error {
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return err
 	}
-	return abPath
+	Level.SetLevel(l)
+	return nil
 }
@@ -0,0 +1,95 @@
+package log
+
+import (
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// packageLevel level of the packages below prefix
+type packageLevel struct {
+	prefix string
+	level  zapcore.Level
+}
+
+// packageLevels longest prefix first, so the most specific override wins
+type packageLevels []packageLevel
+
+func newPackageLevels(packages map[string]string) (packageLevels, error) {
+	res := make(packageLevels, 0, len(packages))
+	for prefix, level := range packages {
+		l := zapcore.InfoLevel
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return nil, err
+		}
+		res = append(res, packageLevel{prefix: prefix, level: l})
+	}
+	sort.Slice(res, func(i, j int) bool { return len(res[i].prefix) > len(res[j].prefix) })
+	return res, nil
+}
+
+// lookup the override for the package of function, e.g. pledge-backend/db.RedisGet
+func (p packageLevels) lookup(function string) (zapcore.Level, bool) {
+	pkg := function
+	if slash := strings.LastIndex(pkg, "/"); slash >= 0 {
+		if dot := strings.Index(pkg[slash:], "."); dot >= 0 {
+			pkg = pkg[:slash+dot]
+		}
+	} else if dot := strings.Index(pkg, "."); dot >= 0 {
+		pkg = pkg[:dot]
+	}
+	for _, override := range p {
+		if pkg == override.prefix || strings.HasPrefix(pkg, override.prefix+"/") {
+			return override.level, true
+		}
+	}
+	return 0, false
+}
+
+// min lowest level any package may log at
+func (p packageLevels) min(level zapcore.Level) zapcore.Level {
+	for _, override := range p {
+		if override.level < level {
+			level = override.level
+		}
+	}
+	return level
+}
+
+// packageCore filter entries by the level of the package that logged them. The caller is only known
+// once the entry is written, so Check lets through whatever some package may log and Write decides
+type packageCore struct {
+	zapcore.Core
+	level    zap.AtomicLevel
+	packages packageLevels
+}
+
+func (c *packageCore) Enabled(level zapcore.Level) bool {
+	return level >= c.packages.min(c.level.Level())
+}
+
+func (c *packageCore) With(fields []zapcore.Field) zapcore.Core {
+	return &packageCore{Core: c.Core.With(fields), level: c.level, packages: c.packages}
+}
+
+func (c *packageCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *packageCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	level := c.level.Level()
+	if len(c.packages) > 0 && ent.Caller.Defined {
+		if override, ok := c.packages.lookup(ent.Caller.Function); ok {
+			level = override
+		}
+	}
+	if ent.Level < level {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
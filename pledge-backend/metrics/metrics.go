@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"pledge-backend/log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefix of every metric, e.g. pledge_http_requests_total
+const namespace = "pledge"
+
+var (
+	HttpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HttpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	JobRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_runs_total",
+		Help:      "Scheduler job runs by job and result (success, failure, aborted).",
+	}, []string{"job", "result"})
+
+	JobRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_retries_total",
+		Help:      "Scheduler job attempts that failed and were retried.",
+	}, []string{"job"})
+
+	JobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Scheduler job run duration.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"job"})
+
+	RpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_requests_total",
+		Help:      "JSON-RPC calls to the chain nodes by chain id and method.",
+	}, []string{"chain", "method"})
+
+	RpcErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_errors_total",
+		Help:      "Failed JSON-RPC calls by chain id and method, reverted calls are not counted.",
+	}, []string{"chain", "method"})
+
+	RpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_request_duration_seconds",
+		Help:      "JSON-RPC round trip latency by chain id and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"chain", "method"})
+
+	WsConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ws_connections",
+		Help:      "Open push connections by transport (websocket, sse).",
+	}, []string{"transport"})
+
+	WsMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ws_messages_total",
+		Help:      "Push messages by transport and direction (in, out).",
+	}, []string{"transport", "direction"})
+
+	WsPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ws_published_total",
+		Help:      "Events published to the hub.",
+	})
+
+	WsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ws_dropped_total",
+		Help:      "Connections dropped by the hub by reason (evicted, rejected).",
+	}, []string{"reason"})
+
+	OraclePriceUpdated = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "oracle_price_updated_timestamp_seconds",
+		Help:      "Unix time the oracle price of a token was last read from chain.",
+	}, []string{"chain", "symbol"})
+
+	EmailSends = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "email_sends_total",
+		Help:      "Emails sent by result (success, failure).",
+	}, []string{"result"})
+)
+
+// Result label value of err
+func Result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// Handler the /metrics handler
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Serve /metrics on addr until ctx is cancelled, for processes without an http server of their own
+func Serve(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	log.Logger.Sugar().Info("metrics listening on ", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Logger.Sugar().Error("metrics server err ", err)
+	}
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterPriceAge export the age of the exchange price of each symbol, read at scrape time.
+// A symbol that never got a price reports +Inf so an age alert fires for it too
+func RegisterPriceAge(symbols []string, age func(symbol string) (time.Duration, bool)) {
+	for _, symbol := range symbols {
+		symbol := symbol
+		prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "price_feed_age_seconds",
+			Help:        "Seconds since the exchange price of a symbol was last updated.",
+			ConstLabels: prometheus.Labels{"symbol": symbol},
+		}, func() float64 {
+			if d, ok := age(symbol); ok {
+				return d.Seconds()
+			}
+			return math.Inf(1)
+		}))
+	}
+}
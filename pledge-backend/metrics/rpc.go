@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"pledge-backend/tracing"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DialEth connect to the node at rawurl, calls over http(s) are counted and timed under chain
+// and traced below the span in the call's ctx.
+// Websocket and ipc endpoints are dialed as is, without rpc metrics
+func DialEth(ctx context.Context, chain string, rawurl string) (*ethclient.Client, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ethclient.DialContext(ctx, rawurl)
+	}
+	c, err := rpc.DialHTTPWithClient(rawurl, &http.Client{Transport: &rpcTransport{chain: chain, base: http.DefaultTransport}})
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(c), nil
+}
+
+// rpcMessage the fields of a json-rpc request or response the metrics need
+type rpcMessage struct {
+	Method string `json:"method"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// failed a json-rpc error other than a reverted call, a revert is an answer, not a node problem
+func (m rpcMessage) failed() bool {
+	return m.Error != nil && !strings.Contains(strings.ToLower(m.Error.Message), "revert")
+}
+
+type rpcTransport struct {
+	chain string
+	base  http.RoundTripper
+}
+
+func (t *rpcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := "unknown"
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			method = rpcMethod(body)
+			_ = body.Close()
+		}
+	}
+
+	_, span := tracing.StartChild(req.Context(), "rpc "+method, attribute.String("rpc.system", "jsonrpc"),
+		attribute.String("rpc.method", method), attribute.String("chain_id", t.chain))
+	start := time.Now()
+	res, err := t.base.RoundTrip(req)
+	failed := err != nil || res.StatusCode >= http.StatusBadRequest
+	if !failed {
+		// the json-rpc error is in the body, read it here so the latency covers the whole response
+		var body []byte
+		body, err = ioutil.ReadAll(res.Body)
+		_ = res.Body.Close()
+		res.Body = ioutil.NopCloser(bytes.NewReader(body))
+		failed = err != nil || rpcFailed(body)
+		if err != nil {
+			res = nil
+		}
+	}
+
+	RpcRequests.WithLabelValues(t.chain, method).Inc()
+	RpcDuration.WithLabelValues(t.chain, method).Observe(time.Since(start).Seconds())
+	if failed {
+		RpcErrors.WithLabelValues(t.chain, method).Inc()
+	}
+	spanErr := err
+	if spanErr == nil && failed {
+		spanErr = errors.New("rpc call failed")
+		if res != nil && res.StatusCode >= http.StatusBadRequest {
+			spanErr = errors.New("rpc call failed: " + res.Status)
+		}
+	}
+	tracing.End(span, spanErr)
+	return res, err
+}
+
+// rpcMethod method of a single call, "batch" for a batch
+func rpcMethod(body io.Reader) string {
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "unknown"
+	}
+	content = bytes.TrimSpace(content)
+	if len(content) > 0 && content[0] == '[' {
+		return "batch"
+	}
+	msg := rpcMessage{}
+	if json.Unmarshal(content, &msg) != nil || msg.Method == "" {
+		return "unknown"
+	}
+	return msg.Method
+}
+
+func rpcFailed(body []byte) bool {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []rpcMessage
+		if json.Unmarshal(body, &batch) != nil {
+			return true
+		}
+		for _, msg := range batch {
+			if msg.failed() {
+				return true
+			}
+		}
+		return false
+	}
+	msg := rpcMessage{}
+	if json.Unmarshal(body, &msg) != nil {
+		return true
+	}
+	return msg.failed()
+}
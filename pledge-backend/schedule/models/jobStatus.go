@@ -0,0 +1,91 @@
+package models
+
+import (
+	"encoding/json"
+	"pledge-backend/db"
+)
+
+// JobRunKey redis hash job name -> JobRun, written by the scheduler whenever a job starts, ends or is rescheduled, read by the api status endpoint
+const JobRunKey = "pledge:job_run"
+
+// ChainSyncKey redis hash chain id -> ChainSync
+const ChainSyncKey = "pledge:chain_sync"
+
+// JobRun state of a scheduler job, times in unix seconds. Duration, attempts and error are those of the last finished run
+type JobRun struct {
+	Name        string `json:"name"`
+	Schedule    string `json:"schedule"` // "every 2m0s" or the cron expression
+	Running     bool   `json:"running"`
+	LastRun     int64  `json:"lastRun"` // start of the latest run, the current one while running
+	NextRun     int64  `json:"nextRun"`
+	LastSuccess int64  `json:"lastSuccess"` // last run that finished without an error and without being aborted
+	DurationMs  int64  `json:"durationMs"`
+	Attempts    int    `json:"attempts"`        // more than 1 when the last run was retried
+	Aborted     bool   `json:"aborted"`         // the last run was cut short by shutdown
+	Error       string `json:"error,omitempty"` // why the last run failed
+}
+
+// ChainSync block the pool sync last read a chain at
+type ChainSync struct {
+	ChainId  string `json:"chainId"`
+	Block    uint64 `json:"block"`
+	SyncedAt int64  `json:"syncedAt"` // unix seconds
+}
+
+// SaveJobRun record run, keeping LastSuccess of the previous run when this one failed or was aborted
+func SaveJobRun(run JobRun) error {
+	if run.LastSuccess == 0 {
+		if prev, err := db.RedisGetHash(JobRunKey); err == nil {
+			last := JobRun{}
+			if json.Unmarshal([]byte(prev[run.Name]), &last) == nil {
+				run.LastSuccess = last.LastSuccess
+			}
+		}
+	}
+	value, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	return db.RedisSetHash(JobRunKey, map[string]string{run.Name: string(value)}, nil)
+}
+
+// JobRuns every recorded job
+func JobRuns() (map[string]JobRun, error) {
+	values, err := db.RedisGetHash(JobRunKey)
+	if err != nil {
+		return nil, err
+	}
+	runs := map[string]JobRun{}
+	for name, value := range values {
+		run := JobRun{}
+		if json.Unmarshal([]byte(value), &run) == nil {
+			runs[name] = run
+		}
+	}
+	return runs, nil
+}
+
+// SaveChainSync record the block a completed pool sync started from
+func SaveChainSync(sync ChainSync) error {
+	value, err := json.Marshal(sync)
+	if err != nil {
+		return err
+	}
+	return db.RedisSetHash(ChainSyncKey, map[string]string{sync.ChainId: string(value)}, nil)
+}
+
+// ChainSyncs every recorded chain
+func ChainSyncs() (map[string]ChainSync, error) {
+	values, err := db.RedisGetHash(ChainSyncKey)
+	if err != nil {
+		return nil, err
+	}
+	syncs := map[string]ChainSync{}
+	for chainId, value := range values {
+		sync := ChainSync{}
+		if json.Unmarshal([]byte(value), &sync) == nil {
+			syncs[chainId] = sync
+		}
+	}
+	return syncs, nil
+}
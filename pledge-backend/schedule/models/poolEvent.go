@@ -0,0 +1,20 @@
+package models
+
+import "pledge-backend/utils"
+
+// PoolChangeChannel redis channel the scheduler publishes pool changes on
+const PoolChangeChannel = "pledge:pool_change"
+
+const (
+	PoolChangeBase = "base"
+	PoolChangeData = "data"
+)
+
+// PoolChangeEvent changed fields of one pool
+type PoolChangeEvent struct {
+	ChainId string                       `json:"chainId"`
+	PoolId  string                       `json:"poolId"`
+	Kind    string                       `json:"kind"` // base or data
+	Changes map[string]utils.FieldChange `json:"changes"`
+	Ts      int64                        `json:"ts"` // ms
+}
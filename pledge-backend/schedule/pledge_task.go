@@ -1,13 +1,31 @@
 package main
 
 import (
+	"context"
+	"os/signal"
+	"pledge-backend/config"
 	"pledge-backend/db"
+	"pledge-backend/log"
+	"pledge-backend/metrics"
 	"pledge-backend/schedule/models"
 	"pledge-backend/schedule/tasks"
+	"pledge-backend/tracing"
+	"syscall"
+	"time"
 )
 
 func main() {
 
+	// root context, cancelled on SIGTERM / SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	// reload live settings when the config file changes or on SIGHUP
+	go config.Watch(ctx, log.Logger.Sugar())
+
+	// tracer provider, every job run is a trace
+	shutdownTracing := tracing.Init("pledge-task")
+
 	// init mysql
 	db.InitMysql()
 
@@ -17,12 +35,24 @@ func main() {
 	// create table
 	models.InitTable()
 
-	// pool task
-	tasks.Task()
+	// prometheus scrape endpoint, the scheduler has no http server of its own
+	if config.Config.Env.TaskMetricsPort != "" {
+		go metrics.Serve(ctx, ":"+config.Config.Env.TaskMetricsPort)
+	}
+
+	// pool task, returns once the running job finished or was aborted
+	tasks.Task(ctx)
+
+	db.Close()
 
+	flushCtx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Config.Env.ShutdownTimeout)*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		log.Logger.Sugar().Warn("tracing flush err ", err)
+	}
 }
 
 /*
- If you change the version, you need to modify the following files'
- config/init.go
+ The config file is config/configV21.toml unless --config <file> or PLEDGE_CONFIG names another one,
+ e.g. config/configV22.toml for version 22, see config/env.go for PLEDGE_* overrides
 */
@@ -9,7 +9,6 @@ import (
 	"pledge-backend/utils"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/shopspring/decimal"
 )
 
@@ -20,27 +19,31 @@ func NewBalanceMonitor() *BalanceMonitor {
 	return &BalanceMonitor{}
 }
 
-// Monitor Sending email when balance is insufficient
-func (s *BalanceMonitor) Monitor() {
+// Monitor Sending email when balance is insufficient, returns an error when the balance could not be read or the alert not sent
+func (s *BalanceMonitor) Monitor(ctx context.Context) error {
 
 	//check on bsc test-net
-	tokenPoolBalance, err := s.GetBalance(config.Config.TestNet.NetUrl, config.Config.TestNet.PledgePoolToken)
-	thresholdPoolToken, ok := new(big.Int).SetString(config.Config.Threshold.PledgePoolTokenThresholdBnb, 10)
-	if ok && (err == nil) && (tokenPoolBalance.Cmp(thresholdPoolToken) <= 0) {
+	tokenPoolBalance, err := s.GetBalance(ctx, config.Config.TestNet.NetUrl, config.Config.TestNet.PledgePoolToken)
+	if err != nil {
+		return err
+	}
+	thresholdPoolToken, ok := new(big.Int).SetString(config.Current().Threshold.PledgePoolTokenThresholdBnb, 10)
+	if ok && (tokenPoolBalance.Cmp(thresholdPoolToken) <= 0) {
 		emailBody, err := s.EmailBody(config.Config.TestNet.PledgePoolToken, "TBNB", tokenPoolBalance.String(), thresholdPoolToken.String())
 		if err != nil {
 			log.Logger.Error(err.Error())
-		} else {
-			err = utils.SendEmail(emailBody, 2)
-			if err != nil {
-				log.Logger.Error(err.Error())
-			}
+			return err
+		}
+		err = utils.SendEmail(emailBody, 2)
+		if err != nil {
+			log.Logger.Error(err.Error())
+			return err
 		}
 	}
 
 	//check on bsc main-net
-	// tokenPoolBalance, err = s.GetBalance(config.Config.MainNet.NetUrl, config.Config.MainNet.PledgePoolToken)
-	// thresholdPoolToken, ok = new(big.Int).SetString(config.Config.Threshold.PledgePoolTokenThresholdBnb, 10)
+	// tokenPoolBalance, err = s.GetBalance(ctx, config.Config.MainNet.NetUrl, config.Config.MainNet.PledgePoolToken)
+	// thresholdPoolToken, ok = new(big.Int).SetString(config.Current().Threshold.PledgePoolTokenThresholdBnb, 10)
 	// if ok && (err == nil) && (tokenPoolBalance.Cmp(thresholdPoolToken) <= 0) {
 	// 	emailBody, err := s.EmailBody(config.Config.MainNet.PledgePoolToken, "BNB", tokenPoolBalance.String(), thresholdPoolToken.String())
 	// 	if err != nil {
@@ -52,19 +55,20 @@ func (s *BalanceMonitor) Monitor() {
 	// 		}
 	// 	}
 	// }
+	return nil
 }
 
 // GetBalance get balance of ERC20 token
-func (s *BalanceMonitor) GetBalance(netUrl, token string) (*big.Int, error) {
+func (s *BalanceMonitor) GetBalance(ctx context.Context, netUrl, token string) (*big.Int, error) {
 
-	ethereumClient, err := ethclient.Dial(netUrl)
+	ethereumClient, err := dial(ctx, netUrl)
 	if err != nil {
 		log.Logger.Error(err.Error())
 		return big.NewInt(0), err
 	}
 	defer ethereumClient.Close()
 
-	balance, err := ethereumClient.BalanceAt(context.Background(), common.HexToAddress(token), nil)
+	balance, err := ethereumClient.BalanceAt(ctx, common.HexToAddress(token), nil)
 	if err != nil {
 		log.Logger.Error(err.Error())
 		return big.NewInt(0), err
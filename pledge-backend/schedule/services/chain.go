@@ -0,0 +1,21 @@
+package services
+
+import (
+	"context"
+	"pledge-backend/config"
+	"pledge-backend/metrics"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// dial rpc connection to network, its calls are counted under the chain configured with that url
+func dial(ctx context.Context, network string) (*ethclient.Client, error) {
+	chainId := "unknown"
+	switch network {
+	case config.Config.TestNet.NetUrl:
+		chainId = config.Config.TestNet.ChainId
+	case config.Config.MainNet.NetUrl:
+		chainId = config.Config.MainNet.ChainId
+	}
+	return metrics.DialEth(ctx, chainId, network)
+}
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"math/big"
 	"pledge-backend/config"
 	"pledge-backend/contract/bindings"
@@ -10,9 +12,10 @@ import (
 	"pledge-backend/schedule/models"
 	"pledge-backend/utils"
 	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 // poolService 资金池服务结构体，负责链上数据同步
@@ -24,58 +27,75 @@ func NewPool() *poolService {
 }
 
 // UpdateAllPoolInfo 更新所有资金池信息（测试网和主网）
-// 目前仅启用测试网同步，主网代码已被注释
-func (s *poolService) UpdateAllPoolInfo() {
+// 目前仅启用测试网同步，主网代码已被注释，ctx 取消后在两个资金池之间停止
+func (s *poolService) UpdateAllPoolInfo(ctx context.Context) error {
 	// 更新测试网资金池信息
-	s.UpdatePoolInfo(config.Config.TestNet.PledgePoolToken, config.Config.TestNet.NetUrl, config.Config.TestNet.ChainId)
+	return s.UpdatePoolInfo(ctx, config.Config.TestNet.PledgePoolToken, config.Config.TestNet.NetUrl, config.Config.TestNet.ChainId)
 
 	// 主网同步暂时注释，可按需启用
-	// s.UpdatePoolInfo(config.Config.MainNet.PledgePoolToken, config.Config.MainNet.NetUrl, config.Config.MainNet.ChainId)
+	// s.UpdatePoolInfo(ctx, config.Config.MainNet.PledgePoolToken, config.Config.MainNet.NetUrl, config.Config.MainNet.ChainId)
 }
 
 // UpdatePoolInfo 更新指定链的资金池信息
 // contractAddress: 质押池合约地址
 // network: 区块链网络RPC URL
 // chainId: 链标识符
-func (s *poolService) UpdatePoolInfo(contractAddress, network, chainId string) {
+// 连接失败、被中止或有资金池读取、保存失败时返回 error
+func (s *poolService) UpdatePoolInfo(ctx context.Context, contractAddress, network, chainId string) error {
 
 	log.Logger.Sugar().Info("开始更新资金池信息 ", contractAddress+" "+network)
 
 	// 1. 连接区块链网络
-	ethereumConn, err := ethclient.Dial(network)
+	ethereumConn, err := dial(ctx, network)
 	if nil != err {
 		log.Logger.Error(err.Error())
-		return
+		return err
+	}
+
+	// 记录同步开始时的区块高度，全部资金池同步完成后写入状态
+	block, err := ethereumConn.BlockNumber(ctx)
+	if nil != err {
+		log.Logger.Error(err.Error())
+		return err
 	}
 
 	// 2. 创建合约实例
 	pledgePoolToken, err := bindings.NewPledgePoolToken(common.HexToAddress(contractAddress), ethereumConn)
 	if nil != err {
 		log.Logger.Error(err.Error())
-		return
+		return err
 	}
 
+	// 合约调用带上 ctx，可被中止，并记录在任务的 trace 中
+	opts := &bind.CallOpts{Context: ctx}
+
 	// 3. 获取全局费率参数（借款费和贷款费）
-	borrowFee, err := pledgePoolToken.PledgePoolTokenCaller.BorrowFee(nil)
-	lendFee, err := pledgePoolToken.PledgePoolTokenCaller.LendFee(nil)
+	borrowFee, err := pledgePoolToken.PledgePoolTokenCaller.BorrowFee(opts)
+	lendFee, err := pledgePoolToken.PledgePoolTokenCaller.LendFee(opts)
 
 	// 4. 获取资金池总数
-	pLength, err := pledgePoolToken.PledgePoolTokenCaller.PoolLength(nil)
+	pLength, err := pledgePoolToken.PledgePoolTokenCaller.PoolLength(opts)
 	if nil != err {
 		log.Logger.Error(err.Error())
-		return
+		return err
 	}
 
-	// 5. 遍历所有资金池，同步数据
+	// 5. 遍历所有资金池，同步数据，failed 记录读取或保存失败的次数
+	failed := 0
 	for i := 0; i <= int(pLength.Int64())-1; i++ {
+		if ctx.Err() != nil {
+			log.Logger.Sugar().Warn("UpdatePoolInfo aborted ", ctx.Err())
+			return ctx.Err()
+		}
 
 		log.Logger.Sugar().Info("正在更新资金池 ", i)
 		poolId := utils.IntToString(i + 1)
 
 		// 5.1 获取资金池基础信息
-		baseInfo, err := pledgePoolToken.PledgePoolTokenCaller.PoolBaseInfo(nil, big.NewInt(int64(i)))
+		baseInfo, err := pledgePoolToken.PledgePoolTokenCaller.PoolBaseInfo(opts, big.NewInt(int64(i)))
 		if err != nil {
 			log.Logger.Sugar().Info("获取资金池基础信息失败 ", poolId, err)
+			failed++
 			continue
 		}
 
@@ -123,51 +143,69 @@ func (s *poolService) UpdatePoolInfo(contractAddress, network, chainId string) {
 		// 5.5 通过MD5比较判断数据是否有变化
 		hasInfoData, byteBaseInfoStr, baseInfoMd5Str := s.GetPoolMd5(&poolBase, "base_info:pool_"+chainId+"_"+poolId)
 		if !hasInfoData || (baseInfoMd5Str != byteBaseInfoStr) { // 数据不存在或有变化
-			// 保存新的资金池基础信息到数据库
-			err = models.NewPoolBase().SavePoolBase(chainId, poolId, &poolBase)
+			// 保存新的资金池基础信息到数据库，oldPoolBase 保留保存前的记录
+			oldPoolBase := models.NewPoolBase()
+			err = oldPoolBase.SavePoolBase(chainId, poolId, &poolBase)
 			if err != nil {
 				log.Logger.Sugar().Error("保存资金池基础信息失败 ", chainId, poolId)
+				failed++
+			} else {
+				s.PublishPoolChange(chainId, poolId, models.PoolChangeBase, oldPoolBase, &poolBase)
 			}
 			// 更新Redis缓存，设置30分钟过期时间（防止哈希冲突）
 			_ = db.RedisSet("base_info:pool_"+chainId+"_"+poolId, baseInfoMd5Str, 60*30)
 		}
 
 		// 5.6 获取资金池数据信息
-		dataInfo, err := pledgePoolToken.PledgePoolTokenCaller.PoolDataInfo(nil, big.NewInt(int64(i)))
+		dataInfo, err := pledgePoolToken.PledgePoolTokenCaller.PoolDataInfo(opts, big.NewInt(int64(i)))
 		if err != nil {
 			log.Logger.Sugar().Info("获取资金池数据信息失败 ", poolId, err)
+			failed++
 			continue
 		}
 
 		// 5.7 通过MD5比较判断资金池数据是否有变化
-		hasPoolData, byteDataInfoStr, dataInfoMd5Str := s.GetPoolMd5(&poolBase, "data_info:pool_"+chainId+"_"+poolId)
+		poolData := models.PoolData{
+			PoolId:                 poolId,                                   // 资金池ID
+			ChainId:                chainId,                                  // 链ID
+			FinishAmountBorrow:     dataInfo.FinishAmountBorrow.String(),     // 已完成借款金额
+			FinishAmountLend:       dataInfo.FinishAmountLend.String(),       // 已完成贷款金额
+			LiquidationAmounBorrow: dataInfo.LiquidationAmounBorrow.String(), // 清算借款金额
+			LiquidationAmounLend:   dataInfo.LiquidationAmounLend.String(),   // 清算贷款金额
+			SettleAmountBorrow:     dataInfo.SettleAmountBorrow.String(),     // 结算借款金额
+			SettleAmountLend:       dataInfo.SettleAmountLend.String(),       // 结算贷款金额
+		}
+		hasPoolData, byteDataInfoStr, dataInfoMd5Str := s.GetPoolMd5(&poolData, "data_info:pool_"+chainId+"_"+poolId)
 		if !hasPoolData || (dataInfoMd5Str != byteDataInfoStr) { // 数据不存在或有变化
-			poolData := models.PoolData{
-				PoolId:                 poolId,                                   // 资金池ID
-				ChainId:                chainId,                                  // 链ID
-				FinishAmountBorrow:     dataInfo.FinishAmountBorrow.String(),     // 已完成借款金额
-				FinishAmountLend:       dataInfo.FinishAmountLend.String(),       // 已完成贷款金额
-				LiquidationAmounBorrow: dataInfo.LiquidationAmounBorrow.String(), // 清算借款金额
-				LiquidationAmounLend:   dataInfo.LiquidationAmounLend.String(),   // 清算贷款金额
-				SettleAmountBorrow:     dataInfo.SettleAmountBorrow.String(),     // 结算借款金额
-				SettleAmountLend:       dataInfo.SettleAmountLend.String(),       // 结算贷款金额
-			}
 			// 保存资金池数据信息到数据库
-			err = models.NewPoolData().SavePoolData(chainId, poolId, &poolData)
+			oldPoolData := models.NewPoolData()
+			err = oldPoolData.SavePoolData(chainId, poolId, &poolData)
 			if err != nil {
 				log.Logger.Sugar().Error("保存资金池数据信息失败 ", chainId, poolId)
+				failed++
+			} else {
+				s.PublishPoolChange(chainId, poolId, models.PoolChangeData, oldPoolData, &poolData)
 			}
 			// 更新Redis缓存，设置30分钟过期时间
 			_ = db.RedisSet("data_info:pool_"+chainId+"_"+poolId, dataInfoMd5Str, 60*30)
 		}
 	}
+
+	// 6. 记录已同步的区块
+	if err = models.SaveChainSync(models.ChainSync{ChainId: chainId, Block: block, SyncedAt: time.Now().Unix()}); err != nil {
+		log.Logger.Sugar().Error("保存链同步状态失败 ", chainId, err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d pool reads or writes failed on chain %s", failed, chainId)
+	}
+	return nil
 }
 
 // GetPoolMd5 获取资金池信息的MD5哈希值，用于判断数据是否变更
-// baseInfo: 资金池基础信息结构体指针
+// baseInfo: 资金池基础信息或数据信息结构体指针
 // key: Redis缓存键名
 // 返回值: (数据是否存在, 缓存的MD5值, 当前数据的MD5值)
-func (s *poolService) GetPoolMd5(baseInfo *models.PoolBase, key string) (bool, string, string) {
+func (s *poolService) GetPoolMd5(baseInfo interface{}, key string) (bool, string, string) {
 	// 将结构体序列化为JSON并计算MD5
 	baseInfoBytes, _ := json.Marshal(baseInfo)
 	baseInfoMd5Str := utils.Md5(string(baseInfoBytes))
@@ -182,3 +220,27 @@ func (s *poolService) GetPoolMd5(baseInfo *models.PoolBase, key string) (bool, s
 		return false, strings.Trim(string(resInfoBytes), `"`), baseInfoMd5Str
 	}
 }
+
+// PublishPoolChange 通过Redis发布资金池变更，API进程转发给订阅的WebSocket客户端
+// old: 保存前的数据库记录，new: 最新的链上数据
+func (s *poolService) PublishPoolChange(chainId, poolId, kind string, old, new interface{}) {
+	// PoolData 的主键json名为"_"
+	changes, err := utils.DiffFields(old, new, "id", "_", "created_at", "updated_at")
+	if err != nil {
+		log.Logger.Sugar().Error("资金池变更对比失败 ", chainId, poolId, err)
+		return
+	}
+	if len(changes) == 0 {
+		return
+	}
+	err = db.RedisPublish(models.PoolChangeChannel, models.PoolChangeEvent{
+		ChainId: chainId,
+		PoolId:  poolId,
+		Kind:    kind,
+		Changes: changes,
+		Ts:      time.Now().UnixNano() / 1e6,
+	})
+	if err != nil {
+		log.Logger.Sugar().Error("发布资金池变更失败 ", chainId, poolId, err)
+	}
+}
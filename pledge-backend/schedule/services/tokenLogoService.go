@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"pledge-backend/config"
 	"pledge-backend/db"
 	"pledge-backend/log"
@@ -20,24 +22,32 @@ func NewTokenLogo() *TokenLogo {
 	return &TokenLogo{}
 }
 
-func (s *TokenLogo) UpdateTokenLogo() {
+// UpdateTokenLogo refresh remote then local logos, stops between tokens once ctx is cancelled.
+// Returns an error when the remote list could not be read or any logo failed
+func (s *TokenLogo) UpdateTokenLogo(ctx context.Context) error {
 
 	// update remote logo
-	res, err := utils.HttpGet(config.Config.Token.LogoUrl, map[string]string{})
-	if err != nil {
-		log.Logger.Sugar().Info("UpdateTokenLogo HttpGet err", err)
+	failed := 0
+	res, remoteErr := utils.HttpGet(config.Current().Token.LogoUrl, map[string]string{})
+	if remoteErr != nil {
+		log.Logger.Sugar().Info("UpdateTokenLogo HttpGet err", remoteErr)
 	} else {
 		tokenLogoRemote := models.TokenLogoRemote{}
-		err = json.Unmarshal(res, &tokenLogoRemote)
+		err := json.Unmarshal(res, &tokenLogoRemote)
 		if err != nil {
 			log.Logger.Sugar().Error("UpdateTokenLogo json.Unmarshal err ", err)
-			return
+			return err
 		}
 		for _, t := range tokenLogoRemote.Tokens {
+			if ctx.Err() != nil {
+				log.Logger.Sugar().Warn("UpdateTokenLogo aborted ", ctx.Err())
+				return ctx.Err()
+			}
 
 			hasNewData, err := s.CheckLogoData(t.Address, utils.IntToString(t.ChainID), t.LogoURI, t.Symbol)
 			if err != nil {
 				log.Logger.Sugar().Error("UpdateTokenLogo CheckLogoData err ", err)
+				failed++
 				continue
 			}
 
@@ -45,6 +55,7 @@ func (s *TokenLogo) UpdateTokenLogo() {
 				err = s.SaveLogoData(t.Address, utils.IntToString(t.ChainID), t.LogoURI, t.Symbol, t.Decimals)
 				if err != nil {
 					log.Logger.Sugar().Error("UpdateTokenLogo SaveLogoData err ", err)
+					failed++
 					continue
 				}
 			}
@@ -54,11 +65,16 @@ func (s *TokenLogo) UpdateTokenLogo() {
 	//update local logo,Local logos have high weight,so execute later, local logos are divided by name
 	for _, v := range LocalTokenLogo {
 		for _, t := range v {
+			if ctx.Err() != nil {
+				log.Logger.Sugar().Warn("UpdateTokenLogo aborted ", ctx.Err())
+				return ctx.Err()
+			}
 			if t["token"] == "" {
 				continue
 			}
 			hasNewData, err := s.CheckLogoData(t["token"], t["chain_id"], t["logo"], t["symbol"])
 			if err != nil {
+				failed++
 				continue
 			}
 
@@ -66,11 +82,19 @@ func (s *TokenLogo) UpdateTokenLogo() {
 				err = s.SaveLogoData(t["token"], t["chain_id"], t["logo"], t["symbol"], utils.StringToInt(t["decimals"]))
 				if err != nil {
 					log.Logger.Sugar().Error("UpdateTokenLogo SaveLogoData err ", err)
+					failed++
 					continue
 				}
 			}
 		}
 	}
+	if remoteErr != nil {
+		return fmt.Errorf("remote logo list: %w", remoteErr)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d token logos failed", failed)
+	}
+	return nil
 }
 
 // CheckLogoData Saving logo data to redis if it has new logo
@@ -10,6 +10,7 @@ import (
 	"pledge-backend/contract/bindings"
 	"pledge-backend/db"
 	"pledge-backend/log"
+	"pledge-backend/metrics"
 	serviceCommon "pledge-backend/schedule/common"
 	"pledge-backend/schedule/models"
 	"pledge-backend/utils"
@@ -18,7 +19,6 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
@@ -29,11 +29,20 @@ func NewTokenPrice() *TokenPrice {
 	return &TokenPrice{}
 }
 
-// UpdateContractPrice update contract price
-func (s *TokenPrice) UpdateContractPrice() {
+// UpdateContractPrice update contract price, stops between tokens once ctx is cancelled.
+// Returns an error when the tokens can not be loaded or any token failed
+func (s *TokenPrice) UpdateContractPrice(ctx context.Context) error {
 	var tokens []models.TokenInfo
-	db.Mysql.Table("token_info").Find(&tokens)
+	if err := db.Mysql.Table("token_info").Find(&tokens).Error; err != nil {
+		log.Logger.Sugar().Error("UpdateContractPrice find tokens err ", err)
+		return err
+	}
+	failed := 0
 	for _, t := range tokens {
+		if ctx.Err() != nil {
+			log.Logger.Sugar().Warn("UpdateContractPrice aborted ", ctx.Err())
+			return ctx.Err()
+		}
 
 		var err error
 		var price int64 = 0
@@ -43,7 +52,7 @@ func (s *TokenPrice) UpdateContractPrice() {
 			continue
 		} else {
 			if t.ChainId == config.Config.TestNet.ChainId {
-				err, price = s.GetTestNetTokenPrice(t.Token)
+				err, price = s.GetTestNetTokenPrice(ctx, t.Token)
 			} else if t.ChainId == "56" {
 				// if strings.ToUpper(t.Token) == config.Config.MainNet.PlgrAddress { // get PLGR price from ku-coin(Only main network price)
 				// 	priceStr, _ := db.RedisGetString("plgr_price")
@@ -61,6 +70,7 @@ func (s *TokenPrice) UpdateContractPrice() {
 
 			if err != nil {
 				log.Logger.Sugar().Error("UpdateContractPrice err ", t.Symbol, t.ChainId, err)
+				failed++
 				continue
 			}
 		}
@@ -68,6 +78,7 @@ func (s *TokenPrice) UpdateContractPrice() {
 		hasNewData, err := s.CheckPriceData(t.Token, t.ChainId, utils.Int64ToString(price))
 		if err != nil {
 			log.Logger.Sugar().Error("UpdateContractPrice CheckPriceData err ", err)
+			failed++
 			continue
 		}
 
@@ -75,19 +86,25 @@ func (s *TokenPrice) UpdateContractPrice() {
 			err = s.SavePriceData(t.Token, t.ChainId, utils.Int64ToString(price))
 			if err != nil {
 				log.Logger.Sugar().Error("UpdateContractPrice SavePriceData err ", err)
+				failed++
 				continue
 			}
 		}
 	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d token prices failed", failed, len(tokens))
+	}
+	return nil
 }
 
 // GetMainNetTokenPrice get contract price on main net
-func (s *TokenPrice) GetMainNetTokenPrice(token string) (error, int64) {
-	ethereumConn, err := ethclient.Dial(config.Config.MainNet.NetUrl)
+func (s *TokenPrice) GetMainNetTokenPrice(ctx context.Context, token string) (error, int64) {
+	ethereumConn, err := dial(ctx, config.Config.MainNet.NetUrl)
 	if nil != err {
 		log.Logger.Error(err.Error())
 		return err, 0
 	}
+	defer ethereumConn.Close()
 
 	bscPledgeOracleMainNetToken, err := bindings.NewBscPledgeOracleMainnetToken(common.HexToAddress(config.Config.MainNet.BscPledgeOracleToken), ethereumConn)
 	if nil != err {
@@ -95,22 +112,24 @@ func (s *TokenPrice) GetMainNetTokenPrice(token string) (error, int64) {
 		return err, 0
 	}
 
-	price, err := bscPledgeOracleMainNetToken.GetPrice(nil, common.HexToAddress(token))
+	price, err := bscPledgeOracleMainNetToken.GetPrice(&bind.CallOpts{Context: ctx}, common.HexToAddress(token))
 	if err != nil {
 		log.Logger.Error(err.Error())
 		return err, 0
 	}
+	metrics.OraclePriceUpdated.WithLabelValues(config.Config.MainNet.ChainId, token).SetToCurrentTime()
 
 	return nil, price.Int64()
 }
 
 // GetTestNetTokenPrice get contract price on test net
-func (s *TokenPrice) GetTestNetTokenPrice(token string) (error, int64) {
-	ethereumConn, err := ethclient.Dial(config.Config.TestNet.NetUrl)
+func (s *TokenPrice) GetTestNetTokenPrice(ctx context.Context, token string) (error, int64) {
+	ethereumConn, err := dial(ctx, config.Config.TestNet.NetUrl)
 	if nil != err {
 		log.Logger.Error(err.Error())
 		return err, 0
 	}
+	defer ethereumConn.Close()
 
 	bscPledgeOracleTestnetToken, err := bindings.NewBscPledgeOracleTestnetToken(common.HexToAddress(config.Config.TestNet.BscPledgeOracleToken), ethereumConn)
 	if nil != err {
@@ -118,11 +137,12 @@ func (s *TokenPrice) GetTestNetTokenPrice(token string) (error, int64) {
 		return err, 0
 	}
 
-	price, err := bscPledgeOracleTestnetToken.GetPrice(nil, common.HexToAddress(token))
+	price, err := bscPledgeOracleTestnetToken.GetPrice(&bind.CallOpts{Context: ctx}, common.HexToAddress(token))
 	if nil != err {
 		log.Logger.Error(err.Error())
 		return err, 0
 	}
+	metrics.OraclePriceUpdated.WithLabelValues(config.Config.TestNet.ChainId, token).SetToCurrentTime()
 
 	return nil, price.Int64()
 }
@@ -208,37 +228,38 @@ func (s *TokenPrice) SavePriceData(token, chainId, price string) error {
 }
 
 // SavePlgrPrice Saving price data to mysql if it has new price
-func (s *TokenPrice) SavePlgrPrice() {
+func (s *TokenPrice) SavePlgrPrice(ctx context.Context) error {
 	priceStr, _ := db.RedisGetString("plgr_price")
 	priceF, _ := decimal.NewFromString(priceStr)
 	e8 := decimal.NewFromInt(100000000)
 	priceF = priceF.Mul(e8)
 	price := priceF.IntPart()
 
-	ethereumConn, err := ethclient.Dial(config.Config.MainNet.NetUrl)
+	ethereumConn, err := dial(ctx, config.Config.MainNet.NetUrl)
 	if nil != err {
 		log.Logger.Error(err.Error())
-		return
+		return err
 	}
+	defer ethereumConn.Close()
 	bscPledgeOracleMainNetToken, err := bindings.NewBscPledgeOracleMainnetToken(common.HexToAddress(config.Config.MainNet.BscPledgeOracleToken), ethereumConn)
 	if nil != err {
 		log.Logger.Error(err.Error())
-		return
+		return err
 	}
 
 	privateKeyEcdsa, err := crypto.HexToECDSA(serviceCommon.PlgrAdminPrivateKey)
 	if err != nil {
 		log.Logger.Error(err.Error())
-		return
+		return err
 	}
 
 	auth, err := bind.NewKeyedTransactorWithChainID(privateKeyEcdsa, big.NewInt(utils.StringToInt64(config.Config.MainNet.ChainId)))
 	if err != nil {
 		log.Logger.Error(err.Error())
-		return
+		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	txCtx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
 
 	transactOpts := bind.TransactOpts{
@@ -250,46 +271,51 @@ func (s *TokenPrice) SavePlgrPrice() {
 		GasFeeCap: nil,
 		GasTipCap: nil,
 		GasLimit:  0,
-		Context:   ctx,
+		Context:   txCtx,
 		NoSend:    false, // Do all transact steps but do not send the transaction
 	}
 
 	_, err = bscPledgeOracleMainNetToken.SetPrice(&transactOpts, common.HexToAddress(config.Config.MainNet.PlgrAddress), big.NewInt(price))
 
 	log.Logger.Sugar().Info("SavePlgrPrice ", err)
+	if err != nil {
+		return err
+	}
 
-	a, d := s.GetMainNetTokenPrice(config.Config.MainNet.PlgrAddress)
+	a, d := s.GetMainNetTokenPrice(ctx, config.Config.MainNet.PlgrAddress)
 	log.Logger.Sugar().Info("GetMainNetTokenPrice ", a, d)
+	return nil
 }
 
 // SavePlgrPriceTestNet  Saving price data to mysql if it has new price
-func (s *TokenPrice) SavePlgrPriceTestNet() {
+func (s *TokenPrice) SavePlgrPriceTestNet(ctx context.Context) error {
 
 	price := 22222
-	ethereumConn, err := ethclient.Dial(config.Config.TestNet.NetUrl)
+	ethereumConn, err := dial(ctx, config.Config.TestNet.NetUrl)
 	if nil != err {
 		log.Logger.Error(err.Error())
-		return
+		return err
 	}
+	defer ethereumConn.Close()
 	bscPledgeOracleTestNetToken, err := bindings.NewBscPledgeOracleMainnetToken(common.HexToAddress(config.Config.TestNet.BscPledgeOracleToken), ethereumConn)
 	if nil != err {
 		log.Logger.Error(err.Error())
-		return
+		return err
 	}
 
 	privateKeyEcdsa, err := crypto.HexToECDSA(serviceCommon.PlgrAdminPrivateKey)
 	if err != nil {
 		log.Logger.Error(err.Error())
-		return
+		return err
 	}
 
 	auth, err := bind.NewKeyedTransactorWithChainID(privateKeyEcdsa, big.NewInt(utils.StringToInt64(config.Config.TestNet.ChainId)))
 	if err != nil {
 		log.Logger.Error(err.Error())
-		return
+		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	txCtx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
 
 	transactOpts := bind.TransactOpts{
@@ -301,14 +327,18 @@ func (s *TokenPrice) SavePlgrPriceTestNet() {
 		GasFeeCap: nil,
 		GasTipCap: nil,
 		GasLimit:  0,
-		Context:   ctx,
+		Context:   txCtx,
 		NoSend:    false, // Do all transact steps but do not send the transaction
 	}
 
 	_, err = bscPledgeOracleTestNetToken.SetPrice(&transactOpts, common.HexToAddress(config.Config.TestNet.PlgrAddress), big.NewInt(int64(price)))
 
 	log.Logger.Sugar().Info("SavePlgrPrice ", err)
+	if err != nil {
+		return err
+	}
 
-	a, d := s.GetTestNetTokenPrice(config.Config.TestNet.PlgrAddress)
+	a, d := s.GetTestNetTokenPrice(ctx, config.Config.TestNet.PlgrAddress)
 	fmt.Println(a, d, 5555)
+	return nil
 }
@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"pledge-backend/config"
 	abifile "pledge-backend/contract/abi"
@@ -15,7 +17,6 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"gorm.io/gorm"
 )
 
@@ -27,11 +28,19 @@ func NewTokenSymbol() *TokenSymbol {
 	return &TokenSymbol{}
 }
 
-// UpdateContractSymbol get contract symbol / 更新代币合约符号
-func (s *TokenSymbol) UpdateContractSymbol() {
+// UpdateContractSymbol get contract symbol / 更新代币合约符号，ctx 取消后在两个代币之间停止，有代币失败时返回 error
+func (s *TokenSymbol) UpdateContractSymbol(ctx context.Context) error {
 	var tokens []models.TokenInfo
-	db.Mysql.Table("token_info").Find(&tokens)
+	if err := db.Mysql.Table("token_info").Find(&tokens).Error; err != nil {
+		log.Logger.Sugar().Error("UpdateContractSymbol find tokens err ", err)
+		return err
+	}
+	failed := 0
 	for _, t := range tokens {
+		if ctx.Err() != nil {
+			log.Logger.Sugar().Warn("UpdateContractSymbol aborted ", ctx.Err())
+			return ctx.Err()
+		}
 		if t.Token == "" {
 			log.Logger.Sugar().Error("UpdateContractSymbol token empty", t.Symbol, t.ChainId)
 			continue
@@ -40,23 +49,25 @@ func (s *TokenSymbol) UpdateContractSymbol() {
 		symbol := ""
 		// 根据链ID决定从测试网或主网获取代币符号
 		if t.ChainId == config.Config.TestNet.ChainId {
-			err, symbol = s.GetContractSymbolOnTestNet(t.Token, config.Config.TestNet.NetUrl)
+			err, symbol = s.GetContractSymbolOnTestNet(ctx, t.Token, config.Config.TestNet.NetUrl)
 		} else if t.ChainId == config.Config.MainNet.ChainId {
 			// 主网需要检查ABI文件是否存在
 			if t.AbiFileExist == 0 {
 				err = s.GetRemoteAbiFileByToken(t.Token, t.ChainId)
 				if err != nil {
 					log.Logger.Sugar().Error("UpdateContractSymbol GetRemoteAbiFileByToken err ", t.Symbol, t.ChainId, err)
+					failed++
 					continue
 				}
 			}
-			err, symbol = s.GetContractSymbolOnTestNet(t.Token, config.Config.MainNet.NetUrl)
+			err, symbol = s.GetContractSymbolOnTestNet(ctx, t.Token, config.Config.MainNet.NetUrl)
 		} else {
 			log.Logger.Sugar().Error("UpdateContractSymbol chain_id err ", t.Symbol, t.ChainId)
 			continue
 		}
 		if err != nil {
 			log.Logger.Sugar().Error("UpdateContractSymbol err ", t.Symbol, t.ChainId, err)
+			failed++
 			continue
 		}
 
@@ -64,6 +75,7 @@ func (s *TokenSymbol) UpdateContractSymbol() {
 		hasNewData, err := s.CheckSymbolData(t.Token, t.ChainId, symbol)
 		if err != nil {
 			log.Logger.Sugar().Error("UpdateContractSymbol CheckSymbolData err ", err)
+			failed++
 			continue
 		}
 
@@ -72,10 +84,15 @@ func (s *TokenSymbol) UpdateContractSymbol() {
 			err = s.SaveSymbolData(t.Token, t.ChainId, symbol)
 			if err != nil {
 				log.Logger.Sugar().Error("UpdateContractSymbol SaveSymbolData err ", err)
+				failed++
 				continue
 			}
 		}
 	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d token symbols failed", failed, len(tokens))
+	}
+	return nil
 }
 
 // GetRemoteAbiFileByToken get and save remote abi file on main net / 从远程获取并保存主网的ABI文件
@@ -144,13 +161,14 @@ func (s *TokenSymbol) FormatAbiJsonStr(result string) string {
 }
 
 // GetContractSymbolOnMainNet get contract symbol on main net / 在主网上获取代币合约符号
-func (s *TokenSymbol) GetContractSymbolOnMainNet(token, network string) (error, string) {
+func (s *TokenSymbol) GetContractSymbolOnMainNet(ctx context.Context, token, network string) (error, string) {
 	// 连接以太坊网络
-	ethereumConn, err := ethclient.Dial(network)
+	ethereumConn, err := dial(ctx, network)
 	if nil != err {
 		log.Logger.Sugar().Error("GetContractSymbolOnMainNet err ", token, err)
 		return err, ""
 	}
+	defer ethereumConn.Close()
 	// 通过代币地址获取ABI
 	abiStr, err := abifile.GetAbiByToken(token)
 	if err != nil {
@@ -172,7 +190,7 @@ func (s *TokenSymbol) GetContractSymbolOnMainNet(token, network string) (error,
 
 	// 调用合约的symbol方法
 	res := make([]interface{}, 0)
-	err = contract.Call(nil, &res, "symbol")
+	err = contract.Call(&bind.CallOpts{Context: ctx}, &res, "symbol")
 	if err != nil {
 		log.Logger.Sugar().Error("GetContractSymbolOnMainNet err ", err)
 		return err, ""
@@ -182,13 +200,14 @@ func (s *TokenSymbol) GetContractSymbolOnMainNet(token, network string) (error,
 }
 
 // GetContractSymbolOnTestNet get contract symbol on test net / 在测试网上获取代币合约符号
-func (s *TokenSymbol) GetContractSymbolOnTestNet(token, network string) (error, string) {
+func (s *TokenSymbol) GetContractSymbolOnTestNet(ctx context.Context, token, network string) (error, string) {
 	// 连接以太坊网络
-	ethereumConn, err := ethclient.Dial(network)
+	ethereumConn, err := dial(ctx, network)
 	if nil != err {
 		log.Logger.Sugar().Error("GetContractSymbolOnMainNet err ", token, err)
 		return err, ""
 	}
+	defer ethereumConn.Close()
 	// 使用标准的ERC20 ABI文件
 	abiStr, err := abifile.GetAbiByToken("erc20")
 	if err != nil {
@@ -210,7 +229,7 @@ func (s *TokenSymbol) GetContractSymbolOnTestNet(token, network string) (error,
 
 	// 调用合约的symbol方法
 	res := make([]interface{}, 0)
-	err = contract.Call(nil, &res, "symbol")
+	err = contract.Call(&bind.CallOpts{Context: ctx}, &res, "symbol")
 	if err != nil {
 		log.Logger.Sugar().Error("GetContractSymbolOnMainNet err ", token, err)
 		return err, ""
@@ -0,0 +1,124 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"pledge-backend/config"
+	"pledge-backend/log"
+	"pledge-backend/schedule/models"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Job a scheduler job, configured by its [schedule] entry
+type Job struct {
+	name   string
+	conf   func(config.ScheduleConfig) config.JobConfig
+	run    func(ctx context.Context) error
+	reload chan struct{} // the [schedule] entry changed
+
+	mu    sync.Mutex
+	state models.JobRun // published to redis on every change, read by the api status endpoint
+}
+
+// registered names taken by register, the jobs themselves are held by Task and their state lives in redis
+var registered = struct {
+	sync.Mutex
+	names map[string]bool
+}{names: map[string]bool{}}
+
+// register add a job, conf picks its entry out of [schedule]
+func register(name string, conf func(config.ScheduleConfig) config.JobConfig, run func(ctx context.Context) error) *Job {
+	j := &Job{name: name, conf: conf, run: run, reload: make(chan struct{}, 1), state: models.JobRun{Name: name}}
+	registered.Lock()
+	defer registered.Unlock()
+	if registered.names[name] {
+		panic("job " + name + " registered twice")
+	}
+	registered.names[name] = true
+	return j
+}
+
+// restore carry the run recorded by the previous process over, a run it left marked running never finished
+func (j *Job) restore(run models.JobRun) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	run.Name, run.Running = j.name, false
+	j.state = run
+}
+
+// update change the job's state and publish it, only the job's own loop calls it so the writes keep their order
+func (j *Job) update(fn func(s *models.JobRun)) {
+	j.mu.Lock()
+	fn(&j.state)
+	state := j.state
+	j.mu.Unlock()
+	if err := models.SaveJobRun(state); err != nil {
+		log.Logger.Sugar().Error("save job run err ", j.name, " ", err)
+	}
+}
+
+// changed tell the job's loop to pick up its new [schedule] entry
+func (j *Job) changed() {
+	select {
+	case j.reload <- struct{}{}:
+	default:
+	}
+}
+
+// plan the job's current [schedule] entry with defaults applied
+func (j *Job) plan() plan {
+	return newPlan(j.conf(config.Current().Schedule), config.Config.Env)
+}
+
+// plan when and how a job runs
+type plan struct {
+	schedule cron.Schedule
+	desc     string
+	timeout  time.Duration
+	jitter   time.Duration
+	retries  int
+	backoff  time.Duration
+}
+
+// newPlan resolve conf, env supplies the interval and timeout a job leaves at 0
+func newPlan(conf config.JobConfig, env config.EnvConfig) plan {
+	p := plan{
+		timeout: time.Duration(conf.Timeout) * time.Second,
+		jitter:  time.Duration(conf.Jitter) * time.Second,
+		retries: conf.Retries,
+		backoff: time.Duration(conf.Backoff) * time.Second,
+	}
+	if p.timeout == 0 {
+		p.timeout = time.Duration(env.TaskExtendDuration) * time.Minute
+	}
+	if conf.Cron != "" {
+		// validated with the config, a bad expression can not get here
+		if schedule, err := cron.ParseStandard(conf.Cron); err == nil {
+			p.schedule, p.desc = schedule, conf.Cron
+			return p
+		}
+	}
+	every := time.Duration(conf.Every) * time.Minute
+	if every == 0 {
+		every = time.Duration(env.TaskDuration) * time.Minute
+	}
+	p.schedule, p.desc = cron.Every(every), fmt.Sprintf("every %s", every)
+	return p
+}
+
+// delay random jitter before a run
+func (p plan) delay() time.Duration {
+	if p.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(p.jitter)))
+}
+
+// retryAfter wait before retry n (1 based), backoff doubled for each further retry
+func (p plan) retryAfter(n int) time.Duration {
+	return p.backoff << (n - 1)
+}
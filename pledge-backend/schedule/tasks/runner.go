@@ -0,0 +1,181 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pledge-backend/log"
+	"pledge-backend/metrics"
+	"pledge-backend/schedule/models"
+	"pledge-backend/tracing"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// abortWait how long an aborted job gets to return before the process exits anyway
+const abortWait = 5 * time.Second
+
+// runner runs jobs so that shutdown can let the current runs finish, or abort them once the deadline passes
+type runner struct {
+	stopping context.Context // cancelled on SIGTERM, no new run starts
+	abort    context.Context // cancelled when the shutdown deadline passes, handed to the jobs
+	cancel   context.CancelFunc
+
+	mu      sync.Mutex
+	running sync.WaitGroup
+}
+
+func newRunner(stopping context.Context) *runner {
+	abort, cancel := context.WithCancel(context.Background())
+	return &runner{stopping: stopping, abort: abort, cancel: cancel}
+}
+
+// loop run j now and then on its schedule until shutdown begins. Runs of a job never overlap:
+// the next run is planned once the previous one returned
+func (r *runner) loop(j *Job) {
+	p := j.plan()
+	r.run(j, p)
+	for {
+		next := p.schedule.Next(time.Now().UTC())
+		j.update(func(s *models.JobRun) {
+			s.Schedule, s.NextRun = p.desc, next.Unix()
+		})
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-r.stopping.Done():
+			timer.Stop()
+			return
+		case <-j.reload:
+			timer.Stop()
+			p = j.plan()
+			continue
+		case <-timer.C:
+		}
+		r.run(j, p)
+	}
+}
+
+// run one run of j after its jitter: attempts until one succeeds, the retries are used up or shutdown begins
+func (r *runner) run(j *Job, p plan) {
+	if !r.sleep(p.delay()) {
+		return
+	}
+	r.mu.Lock()
+	if r.stopping.Err() != nil {
+		r.mu.Unlock()
+		return
+	}
+	r.running.Add(1)
+	r.mu.Unlock()
+	defer r.running.Done()
+
+	start := time.Now()
+	j.update(func(s *models.JobRun) {
+		s.Schedule, s.Running, s.LastRun = p.desc, true, start.Unix()
+	})
+	var err error
+	attempts := 0
+	for {
+		attempts++
+		err = r.attempt(j, p.timeout)
+		if err == nil || r.abort.Err() != nil || attempts > p.retries {
+			break
+		}
+		wait := p.retryAfter(attempts)
+		log.Logger.Sugar().Warn("job ", j.name, " attempt ", attempts, " failed, retrying in ", wait, " ", err)
+		metrics.JobRetries.WithLabelValues(j.name).Inc()
+		if !r.sleep(wait) {
+			break
+		}
+	}
+	if err != nil {
+		log.Logger.Sugar().Error("job ", j.name, " failed in ", time.Since(start), " after ", attempts, " attempts ", err)
+	} else {
+		log.Logger.Sugar().Info("job ", j.name, " finished in ", time.Since(start))
+	}
+	r.record(j, start, attempts, err)
+}
+
+// attempt call the job once with timeout, a panic is returned as an error
+func (r *runner) attempt(j *Job, timeout time.Duration) (err error) {
+	ctx, cancel := context.WithTimeout(r.abort, timeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "job "+j.name, trace.SpanKindInternal, attribute.String("job", j.name))
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic: %v", v)
+			log.Logger.Error("job "+j.name+" panicked", zap.Any("panic", v), zap.ByteString("stack", debug.Stack()))
+		}
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s: %w", timeout, err)
+		}
+		tracing.End(span, err)
+	}()
+	return j.run(ctx)
+}
+
+// sleep d unless shutdown begins first, false then
+func (r *runner) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return r.stopping.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-r.stopping.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// record the finished run for the api status endpoint and the metrics
+func (r *runner) record(j *Job, start time.Time, attempts int, err error) {
+	aborted := r.abort.Err() != nil
+	result := metrics.Result(err)
+	if aborted {
+		result = "aborted"
+	}
+	metrics.JobRuns.WithLabelValues(j.name, result).Inc()
+	metrics.JobDuration.WithLabelValues(j.name).Observe(time.Since(start).Seconds())
+
+	j.update(func(s *models.JobRun) {
+		s.Running, s.DurationMs, s.Attempts, s.Aborted, s.Error = false, time.Since(start).Milliseconds(), attempts, aborted, ""
+		if err != nil && !aborted {
+			s.Error = err.Error()
+		}
+		if err == nil && !aborted {
+			s.LastSuccess = time.Now().Unix()
+		}
+	})
+}
+
+// shutdown wait for the current runs, abort them when they take longer than timeout
+func (r *runner) shutdown(timeout time.Duration) {
+	// stopping is done, every run that got past the check has been counted
+	r.mu.Lock()
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return
+	case <-time.After(timeout):
+	}
+	log.Logger.Sugar().Warn("job still running after ", timeout, ", aborting")
+	r.cancel()
+	select {
+	case <-done:
+	case <-time.After(abortWait):
+		log.Logger.Warn("job did not return after abort, exiting anyway")
+	}
+}
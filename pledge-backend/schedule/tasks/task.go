@@ -1,44 +1,72 @@
 package tasks
 
 import (
+	"context"
+	"pledge-backend/config"
 	"pledge-backend/db"
+	"pledge-backend/log"
 	"pledge-backend/schedule/common"
+	"pledge-backend/schedule/models"
 	"pledge-backend/schedule/services"
 	"time"
-
-	"github.com/jasonlvhit/gocron"
 )
 
-func Task() {
+// cachePrefixes redis keys only the scheduler writes: pool md5s and token info
+var cachePrefixes = []string{"base_info:pool_", "data_info:pool_", "token_info:"}
+
+// Task run every job once, then on its [schedule] entry until ctx is cancelled (SIGTERM),
+// the jobs running at that moment get env.shutdown_timeout to finish before they are aborted
+func Task(ctx context.Context) {
 
 	// get environment variables
 	common.GetEnv()
 
-	// flush redis db
-	err := db.RedisFlushDB()
-	if err != nil {
-		panic("clear redis error " + err.Error())
+	// clear the scheduler's own caches so every pool and token is written again. The redis db is shared
+	// with the api (sessions, siwe nonces, rate limits, job history), never flush it as a whole
+	for _, prefix := range cachePrefixes {
+		if _, err := db.RedisDeletePrefix(prefix); err != nil {
+			panic("clear redis error " + err.Error())
+		}
+	}
+
+	//register jobs
+	jobs := []*Job{
+		register("UpdateAllPoolInfo", func(s config.ScheduleConfig) config.JobConfig { return s.PoolInfo }, services.NewPool().UpdateAllPoolInfo),
+		register("UpdateContractPrice", func(s config.ScheduleConfig) config.JobConfig { return s.ContractPrice }, services.NewTokenPrice().UpdateContractPrice),
+		register("UpdateContractSymbol", func(s config.ScheduleConfig) config.JobConfig { return s.ContractSymbol }, services.NewTokenSymbol().UpdateContractSymbol),
+		register("UpdateTokenLogo", func(s config.ScheduleConfig) config.JobConfig { return s.TokenLogo }, services.NewTokenLogo().UpdateTokenLogo),
+		register("Monitor", func(s config.ScheduleConfig) config.JobConfig { return s.BalanceMonitor }, services.NewBalanceMonitor().Monitor),
+		// register("SavePlgrPrice", func(s config.ScheduleConfig) config.JobConfig { return s.PlgrPrice }, services.NewTokenPrice().SavePlgrPrice),
+		register("SavePlgrPriceTestNet", func(s config.ScheduleConfig) config.JobConfig { return s.PlgrPrice }, services.NewTokenPrice().SavePlgrPriceTestNet),
 	}
 
-	//init task
-	services.NewPool().UpdateAllPoolInfo()
-	services.NewTokenPrice().UpdateContractPrice()
-	services.NewTokenSymbol().UpdateContractSymbol()
-	services.NewTokenLogo().UpdateTokenLogo()
-	services.NewBalanceMonitor().Monitor()
-	// services.NewTokenPrice().SavePlgrPrice()
-	services.NewTokenPrice().SavePlgrPriceTestNet()
-
-	//run pool task
-	s := gocron.NewScheduler()
-	s.ChangeLoc(time.UTC)
-	_ = s.Every(2).Minutes().From(gocron.NextTick()).Do(services.NewPool().UpdateAllPoolInfo)
-	_ = s.Every(1).Minute().From(gocron.NextTick()).Do(services.NewTokenPrice().UpdateContractPrice)
-	_ = s.Every(2).Hours().From(gocron.NextTick()).Do(services.NewTokenSymbol().UpdateContractSymbol)
-	_ = s.Every(2).Hours().From(gocron.NextTick()).Do(services.NewTokenLogo().UpdateTokenLogo)
-	_ = s.Every(30).Minutes().From(gocron.NextTick()).Do(services.NewBalanceMonitor().Monitor)
-	//_ = s.Every(30).Minutes().From(gocron.NextTick()).Do(services.NewTokenPrice().SavePlgrPrice)
-	_ = s.Every(30).Minutes().From(gocron.NextTick()).Do(services.NewTokenPrice().SavePlgrPriceTestNet)
-	<-s.Start() // Start all the pending jobs
+	// carry the recorded runs over a restart, last success and last result stay visible until the jobs ran again
+	if runs, err := models.JobRuns(); err != nil {
+		log.Logger.Sugar().Error("load job runs err ", err)
+	} else {
+		for _, j := range jobs {
+			if run, ok := runs[j.name]; ok {
+				j.restore(run)
+			}
+		}
+	}
+
+	//run jobs, a job picks up its new [schedule] entry after a reload
+	r := newRunner(ctx)
+	for _, j := range jobs {
+		go r.loop(j)
+	}
+	config.Subscribe(func(prev *config.Snapshot, cur *config.Snapshot) {
+		for _, j := range jobs {
+			if j.conf(prev.Schedule) != j.conf(cur.Schedule) {
+				log.Logger.Sugar().Infof("job %s schedule changed to %+v", j.name, j.conf(cur.Schedule))
+				j.changed()
+			}
+		}
+	})
 
+	<-ctx.Done()
+	log.Logger.Info("scheduler stopping")
+	r.shutdown(time.Duration(config.Config.Env.ShutdownTimeout) * time.Second)
+	log.Logger.Info("scheduler stopped")
 }
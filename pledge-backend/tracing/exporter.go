@@ -0,0 +1,239 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// exportTimeout upper bound of one otlp post
+const exportTimeout = 10 * time.Second
+
+// jsonExporter writes every batch as one otlp json line, for local debugging
+type jsonExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newJsonExporter(w io.Writer) *jsonExporter {
+	return &jsonExporter{w: w}
+}
+
+func (e *jsonExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	body, err := json.Marshal(encode(spans))
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.w.Write(append(body, '\n'))
+	return err
+}
+
+func (e *jsonExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// otlpExporter posts batches to an otlp/http endpoint using the json encoding,
+// which every opentelemetry collector accepts on /v1/traces
+type otlpExporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newOtlpExporter(endpoint string) *otlpExporter {
+	return &otlpExporter{endpoint: endpoint, client: &http.Client{Timeout: exportTimeout}}
+}
+
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	body, err := json.Marshal(encode(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("otlp export %s: %s", res.Status, msg)
+	}
+	return nil
+}
+
+func (e *otlpExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// otlp json, see opentelemetry-proto trace/v1/trace.proto. Ids are hex, 64 bit integers strings
+type (
+	exportRequest struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}
+	resourceSpans struct {
+		Resource struct {
+			Attributes []keyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+	scopeSpans struct {
+		Scope struct {
+			Name    string `json:"name"`
+			Version string `json:"version,omitempty"`
+		} `json:"scope"`
+		Spans []span `json:"spans"`
+	}
+	span struct {
+		TraceId           string     `json:"traceId"`
+		SpanId            string     `json:"spanId"`
+		ParentSpanId      string     `json:"parentSpanId,omitempty"`
+		Name              string     `json:"name"`
+		Kind              int        `json:"kind"`
+		StartTimeUnixNano string     `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+		Attributes        []keyValue `json:"attributes,omitempty"`
+		Events            []event    `json:"events,omitempty"`
+		Status            status     `json:"status"`
+	}
+	event struct {
+		TimeUnixNano string     `json:"timeUnixNano"`
+		Name         string     `json:"name"`
+		Attributes   []keyValue `json:"attributes,omitempty"`
+	}
+	status struct {
+		Code    int    `json:"code,omitempty"` // 1 ok, 2 error
+		Message string `json:"message,omitempty"`
+	}
+	keyValue struct {
+		Key   string   `json:"key"`
+		Value anyValue `json:"value"`
+	}
+	anyValue struct {
+		StringValue *string     `json:"stringValue,omitempty"`
+		BoolValue   *bool       `json:"boolValue,omitempty"`
+		IntValue    *string     `json:"intValue,omitempty"`
+		DoubleValue *float64    `json:"doubleValue,omitempty"`
+		ArrayValue  *arrayValue `json:"arrayValue,omitempty"`
+	}
+	arrayValue struct {
+		Values []anyValue `json:"values"`
+	}
+)
+
+// encode group spans by resource and tracer
+func encode(spans []sdktrace.ReadOnlySpan) exportRequest {
+	req := exportRequest{}
+	type scopeKey struct {
+		resource attribute.Distinct
+		name     string
+		version  string
+	}
+	resources := map[attribute.Distinct]int{}
+	scopes := map[scopeKey]int{}
+	for _, s := range spans {
+		resKey := s.Resource().Equivalent()
+		ri, ok := resources[resKey]
+		if !ok {
+			ri = len(req.ResourceSpans)
+			resources[resKey] = ri
+			rs := resourceSpans{}
+			rs.Resource.Attributes = keyValues(s.Resource().Attributes())
+			req.ResourceSpans = append(req.ResourceSpans, rs)
+		}
+		rs := &req.ResourceSpans[ri]
+
+		lib := s.InstrumentationLibrary()
+		key := scopeKey{resKey, lib.Name, lib.Version}
+		si, ok := scopes[key]
+		if !ok {
+			si = len(rs.ScopeSpans)
+			scopes[key] = si
+			ss := scopeSpans{}
+			ss.Scope.Name, ss.Scope.Version = lib.Name, lib.Version
+			rs.ScopeSpans = append(rs.ScopeSpans, ss)
+		}
+		rs.ScopeSpans[si].Spans = append(rs.ScopeSpans[si].Spans, encodeSpan(s))
+	}
+	return req
+}
+
+func encodeSpan(s sdktrace.ReadOnlySpan) span {
+	res := span{
+		TraceId:           s.SpanContext().TraceID().String(),
+		SpanId:            s.SpanContext().SpanID().String(),
+		Name:              s.Name(),
+		Kind:              int(s.SpanKind()),
+		StartTimeUnixNano: strconv.FormatInt(s.StartTime().UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.EndTime().UnixNano(), 10),
+		Attributes:        keyValues(s.Attributes()),
+	}
+	if s.Parent().HasSpanID() {
+		res.ParentSpanId = s.Parent().SpanID().String()
+	}
+	for _, e := range s.Events() {
+		res.Events = append(res.Events, event{
+			TimeUnixNano: strconv.FormatInt(e.Time.UnixNano(), 10),
+			Name:         e.Name,
+			Attributes:   keyValues(e.Attributes),
+		})
+	}
+	switch s.Status().Code {
+	case codes.Ok:
+		res.Status.Code = 1
+	case codes.Error:
+		res.Status.Code = 2
+		res.Status.Message = s.Status().Description
+	}
+	return res
+}
+
+func keyValues(attrs []attribute.KeyValue) []keyValue {
+	res := make([]keyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		res = append(res, keyValue{Key: string(kv.Key), Value: value(kv.Value)})
+	}
+	return res
+}
+
+func value(v attribute.Value) anyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		b := v.AsBool()
+		return anyValue{BoolValue: &b}
+	case attribute.INT64:
+		i := strconv.FormatInt(v.AsInt64(), 10)
+		return anyValue{IntValue: &i}
+	case attribute.FLOAT64:
+		f := v.AsFloat64()
+		return anyValue{DoubleValue: &f}
+	case attribute.STRINGSLICE:
+		values := []anyValue{}
+		for _, item := range v.AsStringSlice() {
+			item := item
+			values = append(values, anyValue{StringValue: &item})
+		}
+		return anyValue{ArrayValue: &arrayValue{Values: values}}
+	}
+	str := v.Emit()
+	return anyValue{StringValue: &str}
+}
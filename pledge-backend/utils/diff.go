@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// FieldChange old and new value of a changed field
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// DiffFields compare two structs by their json fields, returns the changed fields keyed by json name
+func DiffFields(old, new interface{}, ignore ...string) (map[string]FieldChange, error) {
+	oldMap := map[string]interface{}{}
+	newMap := map[string]interface{}{}
+	oldBytes, err := json.Marshal(old)
+	if err != nil {
+		return nil, err
+	}
+	newBytes, err := json.Marshal(new)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(oldBytes, &oldMap); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(newBytes, &newMap); err != nil {
+		return nil, err
+	}
+
+	changes := map[string]FieldChange{}
+	for k, v := range newMap {
+		if IsContain(k, ignore) {
+			continue
+		}
+		if !reflect.DeepEqual(oldMap[k], v) {
+			changes[k] = FieldChange{Old: oldMap[k], New: v}
+		}
+	}
+	return changes, nil
+}
@@ -5,15 +5,17 @@ import (
 	"net/smtp"
 	"net/textproto"
 	"pledge-backend/config"
+	"pledge-backend/metrics"
 )
 
 // SendEmail dataType 1 test, 2 html
 func SendEmail(data []byte, dataType int) error {
+	conf := config.Current().Email
 	e := &email.Email{
-		To:      config.Config.Email.To,      // []string{"test@example.com"},
-		Cc:      config.Config.Email.Cc,      // []string{"test@example.com"},
-		From:    config.Config.Email.From,    // "Jordan Wright <test@gmail.com>",
-		Subject: config.Config.Email.Subject, //"Awesome Subject",
+		To:      conf.To,      // []string{"test@example.com"},
+		Cc:      conf.Cc,      // []string{"test@example.com"},
+		From:    conf.From,    // "Jordan Wright <test@gmail.com>",
+		Subject: conf.Subject, //"Awesome Subject",
 		Headers: textproto.MIMEHeader{},
 	}
 	if dataType == 1 {
@@ -21,16 +23,17 @@ func SendEmail(data []byte, dataType int) error {
 	} else {
 		e.HTML = data
 	}
-	return e.Send(config.Config.Email.Host+":"+config.Config.Email.Port, smtp.PlainAuth("", config.Config.Email.Username, config.Config.Email.Pwd, config.Config.Email.Host))
+	return send(e, conf)
 }
 
 // SendEmailWithAttach dataType 1 test, 2 html
 func SendEmailWithAttach(data []byte, dataType int, filename string) error {
+	conf := config.Current().Email
 	e := &email.Email{
-		To:      config.Config.Email.To,
-		Cc:      config.Config.Email.Cc,
-		From:    config.Config.Email.From,
-		Subject: config.Config.Email.Subject,
+		To:      conf.To,
+		Cc:      conf.Cc,
+		From:    conf.From,
+		Subject: conf.Subject,
 		Headers: textproto.MIMEHeader{},
 	}
 	if dataType == 1 {
@@ -42,5 +45,12 @@ func SendEmailWithAttach(data []byte, dataType int, filename string) error {
 	if err != nil {
 		return err
 	}
-	return e.Send(config.Config.Email.Host+config.Config.Email.Port, smtp.PlainAuth("", config.Config.Email.Username, config.Config.Email.Pwd, config.Config.Email.Host))
+	return send(e, conf)
+}
+
+// send deliver e through the configured smtp server and count the result
+func send(e *email.Email, conf config.EmailConfig) error {
+	err := e.Send(conf.Host+":"+conf.Port, smtp.PlainAuth("", conf.Username, conf.Pwd, conf.Host))
+	metrics.EmailSends.WithLabelValues(metrics.Result(err)).Inc()
+	return err
 }
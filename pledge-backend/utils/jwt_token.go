@@ -1,16 +1,43 @@
 package utils
 
 import (
+	"errors"
 	"github.com/dgrijalva/jwt-go"
 	"pledge-backend/config"
 	"time"
 )
 
-func CreateToken(username string) (string, error) {
-	at := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": username,
-		"exp":      time.Now().Add(time.Hour * 24 * 30).Unix(),
-	})
+// tokenTypeAccess typ claim of access tokens, refresh tokens are opaque and never parsed as jwt
+const tokenTypeAccess = "access"
+
+// TokenClaims identity carried by an admin access token
+type TokenClaims struct {
+	Username    string
+	Role        string
+	Permissions []string
+	SessionId   string
+	Address     string // set when signed in with a wallet (siwe)
+	ChainId     int    // set when signed in with a wallet (siwe)
+	ExpiresAt   int64
+}
+
+// CreateToken sign an access token for claims valid for Jwt.ExpireTime seconds
+func CreateToken(claims TokenClaims) (string, error) {
+	now := time.Now()
+	mapClaims := jwt.MapClaims{
+		"username":    claims.Username,
+		"role":        claims.Role,
+		"permissions": claims.Permissions,
+		"sid":         claims.SessionId,
+		"typ":         tokenTypeAccess,
+		"iat":         now.Unix(),
+		"exp":         now.Add(time.Duration(config.Config.Jwt.ExpireTime) * time.Second).Unix(),
+	}
+	if claims.Address != "" {
+		mapClaims["address"] = claims.Address
+		mapClaims["chain_id"] = claims.ChainId
+	}
+	at := jwt.NewWithClaims(jwt.SigningMethodHS256, mapClaims)
 	token, err := at.SignedString([]byte(config.Config.Jwt.SecretKey))
 	if err != nil {
 		return "", err
@@ -19,11 +46,54 @@ func CreateToken(username string) (string, error) {
 }
 
 func ParseToken(token string, secret string) (string, error) {
-	claim, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+	claims, err := ParseTokenClaims(token, secret)
+	if err != nil {
+		return "", err
+	}
+	return claims.Username, nil
+}
+
+// ParseTokenClaims verify an access token and return its claims, only HS256 is accepted
+func ParseTokenClaims(token string, secret string) (*TokenClaims, error) {
+	parser := jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Alg()}}
+	claim, err := parser.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, errors.New("unexpected signing method")
+		}
 		return []byte(secret), nil
 	})
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	mapClaims, ok := claim.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+	if typ, _ := mapClaims["typ"].(string); typ != tokenTypeAccess {
+		return nil, errors.New("not an access token")
+	}
+	exp, ok := mapClaims["exp"].(float64)
+	if !ok {
+		return nil, errors.New("token without expiry")
+	}
+	res := &TokenClaims{ExpiresAt: int64(exp)}
+	if res.Username, ok = mapClaims["username"].(string); !ok || res.Username == "" {
+		return nil, errors.New("token without username")
+	}
+	if res.SessionId, ok = mapClaims["sid"].(string); !ok || res.SessionId == "" {
+		return nil, errors.New("token without session")
+	}
+	res.Role, _ = mapClaims["role"].(string)
+	res.Address, _ = mapClaims["address"].(string)
+	if chainId, ok := mapClaims["chain_id"].(float64); ok {
+		res.ChainId = int(chainId)
+	}
+	if permissions, ok := mapClaims["permissions"].([]interface{}); ok {
+		for _, p := range permissions {
+			if s, ok := p.(string); ok {
+				res.Permissions = append(res.Permissions, s)
+			}
+		}
 	}
-	return claim.Claims.(jwt.MapClaims)["username"].(string), nil
+	return res, nil
 }
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/url"
+	"pledge-backend/config"
+	"strings"
+)
+
+// redactedKeys keys whose values never reach a log, matched case-insensitively as substrings
+var redactedKeys = []string{"password", "token", "secret", "signature", "authcode", "api_key", "apikey", "privatekey", "private_key"}
+
+// Redacted placeholder of a removed value
+const Redacted = "[REDACTED]"
+
+// IsSensitiveKey whether values under key must be redacted
+func IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range redactedKeys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	if config.Config != nil {
+		for _, k := range config.Config.Log.RedactKeys {
+			if strings.Contains(key, strings.ToLower(k)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Redact v for a log line, a struct or map comes back as its json form with sensitive values replaced.
+// Fields are matched by json name, or by field name when a struct has no json tags
+func Redact(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return Redacted
+	}
+	var res interface{}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return Redacted
+	}
+	return redactValue(res)
+}
+
+// RedactBody a json or form encoded body as json with sensitive values replaced,
+// other bodies are dropped since they can not be inspected
+func RedactBody(body []byte, contentType string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return Redacted
+		}
+		m := map[string]interface{}{}
+		for k, v := range values {
+			if IsSensitiveKey(k) {
+				m[k] = Redacted
+			} else if len(v) == 1 {
+				m[k] = v[0]
+			} else {
+				m[k] = v
+			}
+		}
+		b, _ := json.Marshal(m)
+		return string(b)
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return Redacted
+	}
+	b, _ := json.Marshal(redactValue(v))
+	return string(b)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if IsSensitiveKey(k) {
+				t[k] = Redacted
+			} else {
+				t[k] = redactValue(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range t {
+			t[i] = redactValue(child)
+		}
+	}
+	return v
+}
@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const siweHeader = " wants you to sign in with your Ethereum account:"
+
+// SiweMessage EIP-4361 Sign-In with Ethereum message
+type SiweMessage struct {
+	Domain         string
+	Address        string // EIP-55 checksummed
+	Statement      string
+	Uri            string
+	Version        string
+	ChainId        int
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestId      string
+	Resources      []string
+}
+
+// ParseSiweMessage parse the text a wallet signed, fields must appear in the order of EIP-4361
+func ParseSiweMessage(message string) (*SiweMessage, error) {
+	lines := strings.Split(message, "\n")
+	if len(lines) < 8 {
+		return nil, errors.New("siwe: message too short")
+	}
+	m := &SiweMessage{}
+
+	if !strings.HasSuffix(lines[0], siweHeader) {
+		return nil, errors.New("siwe: invalid header")
+	}
+	m.Domain = strings.TrimSuffix(lines[0], siweHeader)
+	if m.Domain == "" {
+		return nil, errors.New("siwe: empty domain")
+	}
+
+	m.Address = lines[1]
+	if !common.IsHexAddress(m.Address) || common.HexToAddress(m.Address).Hex() != m.Address {
+		return nil, errors.New("siwe: address must be EIP-55 checksummed")
+	}
+
+	// address LF LF [statement LF] LF, some wallets drop the second blank line when there is no statement
+	if lines[2] != "" {
+		return nil, errors.New("siwe: missing blank line after address")
+	}
+	i := 3
+	if !strings.HasPrefix(lines[i], "URI: ") {
+		if lines[i] != "" {
+			m.Statement = lines[i]
+			i++
+		}
+		if i >= len(lines) || lines[i] != "" {
+			return nil, errors.New("siwe: missing blank line after statement")
+		}
+		i++
+	}
+
+	field := func(name string, required bool) (string, error) {
+		prefix := name + ": "
+		if i < len(lines) && strings.HasPrefix(lines[i], prefix) {
+			i++
+			return strings.TrimPrefix(lines[i-1], prefix), nil
+		}
+		if required {
+			return "", fmt.Errorf("siwe: missing %s", name)
+		}
+		return "", nil
+	}
+	var err error
+	var value string
+
+	if m.Uri, err = field("URI", true); err != nil {
+		return nil, err
+	}
+	if m.Version, err = field("Version", true); err != nil {
+		return nil, err
+	}
+	if m.Version != "1" {
+		return nil, errors.New("siwe: unsupported version")
+	}
+	if value, err = field("Chain ID", true); err != nil {
+		return nil, err
+	}
+	if m.ChainId, err = strconv.Atoi(value); err != nil || m.ChainId <= 0 {
+		return nil, errors.New("siwe: invalid chain id")
+	}
+	if m.Nonce, err = field("Nonce", true); err != nil {
+		return nil, err
+	}
+	if len(m.Nonce) < 8 || !isAlphanumeric(m.Nonce) {
+		return nil, errors.New("siwe: invalid nonce")
+	}
+	if value, err = field("Issued At", true); err != nil {
+		return nil, err
+	}
+	if m.IssuedAt, err = time.Parse(time.RFC3339, value); err != nil {
+		return nil, errors.New("siwe: invalid issued at")
+	}
+	if value, _ = field("Expiration Time", false); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errors.New("siwe: invalid expiration time")
+		}
+		m.ExpirationTime = &t
+	}
+	if value, _ = field("Not Before", false); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errors.New("siwe: invalid not before")
+		}
+		m.NotBefore = &t
+	}
+	m.RequestId, _ = field("Request ID", false)
+	if i < len(lines) && lines[i] == "Resources:" {
+		for i++; i < len(lines) && strings.HasPrefix(lines[i], "- "); i++ {
+			m.Resources = append(m.Resources, strings.TrimPrefix(lines[i], "- "))
+		}
+	}
+	if i != len(lines) {
+		return nil, errors.New("siwe: unexpected trailing content")
+	}
+	return m, nil
+}
+
+// ValidAt whether the message is inside its validity window at t
+func (m *SiweMessage) ValidAt(t time.Time) bool {
+	if m.ExpirationTime != nil && !t.Before(*m.ExpirationTime) {
+		return false
+	}
+	if m.NotBefore != nil && t.Before(*m.NotBefore) {
+		return false
+	}
+	return true
+}
+
+func isAlphanumeric(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// RecoverPersonalSign address that produced signature over message with personal_sign (EIP-191 version 0x45)
+func RecoverPersonalSign(message string, signature string) (string, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return "", errors.New("invalid signature")
+	}
+	// wallets return v as 27/28
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	hash := crypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)))
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return "", err
+	}
+	return crypto.PubkeyToAddress(*pub).Hex(), nil
+}